@@ -0,0 +1,68 @@
+package main
+
+import (
+	"SWIFT-Remitly/internal/database"
+	"SWIFT-Remitly/internal/database/migrations"
+	"SWIFT-Remitly/internal/models"
+	"SWIFT-Remitly/internal/parser"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// runDryRun checks the CSV file against the stored bank data and prints what importing it would do.
+// Pending migrations are not applied, so the database is left untouched, the dry run fails if there are any.
+func runDryRun(csvDataPath string, parseOptions []parser.Option) {
+	db, err := database.Connect()
+	if err != nil {
+		log.Fatalf("Error connecting to database: %v", err)
+	}
+	pending, err := migrations.New(db).Pending()
+	if err != nil {
+		log.Fatalf("Error checking migrations: %v", err)
+	}
+	if len(pending) > 0 {
+		log.Fatalf("Error checking migrations: %d pending migrations starting with %04d_%s, run the migrate up command first",
+			len(pending), pending[0].Version, pending[0].Name)
+	}
+
+	report, err := parser.ParseCSV(database.New(db), csvDataPath, append(parseOptions, parser.WithDryRun())...)
+	if err != nil {
+		log.Fatalf("Error parsing csv: %v", err)
+	}
+	printDryRunReport(report)
+}
+
+func printDryRunReport(report models.ImportReport) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(writer, "WOULD BE INSERTED\t%d\n", report.Inserted)
+	fmt.Fprintf(writer, "WOULD BE UPDATED\t%d\n", report.Updated)
+	fmt.Fprintf(writer, "UNCHANGED\t%d\n", report.Unchanged)
+	fmt.Fprintf(writer, "REJECTED\t%d\n", report.Failed)
+	fmt.Fprintf(writer, "BRANCHES WITHOUT HEADQUARTER\t%d\n", len(report.OrphanBranches))
+	_ = writer.Flush()
+
+	if len(report.Rejected) > 0 {
+		fmt.Println()
+		writer = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "LINE\tERROR")
+		for _, row := range report.Rejected {
+			errorsText := row.Error
+			if len(row.Details) > 0 {
+				errorsText += ": " + strings.Join(row.Details, "; ")
+			}
+			fmt.Fprintf(writer, "%d\t%s\n", row.Line, errorsText)
+		}
+		_ = writer.Flush()
+	}
+
+	if len(report.OrphanBranches) > 0 {
+		fmt.Println()
+		fmt.Println("BRANCHES WITHOUT HEADQUARTER")
+		for _, swiftCode := range report.OrphanBranches {
+			fmt.Println(swiftCode)
+		}
+	}
+}
@@ -2,20 +2,32 @@ package main
 
 import (
 	"SWIFT-Remitly/internal/database"
+	"SWIFT-Remitly/internal/models"
 	"SWIFT-Remitly/internal/parser"
 	"SWIFT-Remitly/internal/server"
 	"context"
 	"errors"
+	"flag"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 )
 
 var (
-	csvDataPath = os.Getenv("CSV_FILE_PATH")
+	csvDataPath      = os.Getenv("CSV_FILE_PATH")
+	csvRejectsFormat = os.Getenv("CSV_REJECTS_FORMAT")
+	csvBulkMode      = os.Getenv("CSV_BULK_MODE")
+	csvWorkers       = os.Getenv("CSV_IMPORT_WORKERS")
+	csvHeaderAliases = os.Getenv("CSV_HEADER_ALIASES")
+	csvDelimiter     = os.Getenv("CSV_DELIMITER")
+	csvEncoding      = os.Getenv("CSV_ENCODING")
+	csvFormat        = os.Getenv("CSV_FORMAT")
+	csvSheet         = os.Getenv("CSV_SHEET")
 )
 
 func gracefulShutdown(apiServer *http.Server, done chan bool) {
@@ -42,11 +54,86 @@ func gracefulShutdown(apiServer *http.Server, done chan bool) {
 	done <- true
 }
 
+// logRejectedRows logs the summary of the import and the errors of its rejected rows.
+func logRejectedRows(report models.ImportReport) {
+	log.Printf("Inserted %d, updated %d, unchanged %d and rejected %d rows",
+		report.Inserted, report.Updated, report.Unchanged, report.Failed)
+	for _, row := range report.Rejected {
+		errorsText := row.Error
+		if len(row.Details) > 0 {
+			errorsText += ": " + strings.Join(row.Details, "; ")
+		}
+		log.Printf("Rejected CSV line %d: %s", row.Line, errorsText)
+	}
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+
+	dryRun := flag.Bool("dry-run", false, "print what importing the CSV file would do without storing any data or starting the server")
+	flag.Parse()
+
 	log.Println("Starting app")
 
+	rejectsFormat, err := parser.ParseRejectsFormat(csvRejectsFormat)
+	if err != nil {
+		log.Fatalf("Error parsing csv: %v", err)
+	}
+
+	parseOptions := []parser.Option{parser.WithRejectsFile(rejectsFormat)}
+	if csvBulkMode != "" {
+		bulkMode, err := database.ParseBulkMode(csvBulkMode)
+		if err != nil {
+			log.Fatalf("Error parsing csv: %v", err)
+		}
+		parseOptions = append(parseOptions, parser.WithBulkImport(bulkMode))
+	}
+	if csvWorkers != "" {
+		workers, err := strconv.Atoi(csvWorkers)
+		if err != nil || workers < 1 {
+			log.Fatalf("Error parsing csv: CSV_IMPORT_WORKERS must be a positive number, got %q", csvWorkers)
+		}
+		parseOptions = append(parseOptions, parser.WithWorkers(workers))
+	}
+
+	headerAliases, err := parser.ParseHeaderAliases(csvHeaderAliases)
+	if err != nil {
+		log.Fatalf("Error parsing csv: %v", err)
+	}
+	delimiter, err := parser.ParseDelimiter(csvDelimiter)
+	if err != nil {
+		log.Fatalf("Error parsing csv: %v", err)
+	}
+	encoding, err := parser.ParseEncoding(csvEncoding)
+	if err != nil {
+		log.Fatalf("Error parsing csv: %v", err)
+	}
+	format, err := parser.ParseFormat(csvFormat)
+	if err != nil {
+		log.Fatalf("Error parsing csv: %v", err)
+	}
+	parseOptions = append(parseOptions,
+		parser.WithHeaderAliases(headerAliases),
+		parser.WithDelimiter(delimiter),
+		parser.WithEncoding(encoding),
+		parser.WithFormat(format),
+		parser.WithSheet(csvSheet))
+
+	if *dryRun {
+		runDryRun(csvDataPath, parseOptions)
+		return
+	}
+
 	db := database.New(nil)
-	if err := parser.ParseCSV(db, csvDataPath); err != nil {
+	report, err := parser.ParseCSV(db, csvDataPath, parseOptions...)
+	if errors.Is(err, database.ErrBulkAborted) {
+		// the stored bank data is left unchanged, so the server can still serve it
+		log.Printf("Error parsing csv: %v", err)
+		logRejectedRows(report)
+	} else if err != nil {
 		log.Fatalf("Error parsing csv: %v", err)
 	}
 
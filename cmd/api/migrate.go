@@ -0,0 +1,81 @@
+package main
+
+import (
+	"SWIFT-Remitly/internal/database"
+	"SWIFT-Remitly/internal/database/migrations"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateUsage = `Usage: main migrate <command> [flags]
+
+Commands:
+  up                  apply all pending migrations
+  down [-steps N]     revert the last N applied migrations (default 1)
+  status              show applied and pending migrations`
+
+// runMigrate handles the migrate subcommand with its arguments, e.g. ["down", "-steps", "2"].
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		log.Fatalf("Error connecting to database: %v", err)
+	}
+	migrator := migrations.New(db)
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		if err != nil {
+			log.Fatalf("Error applying migrations: %v", err)
+		}
+		log.Printf("Applied %d migrations", applied)
+	case "down":
+		flags := flag.NewFlagSet("migrate down", flag.ExitOnError)
+		steps := flags.Int("steps", 1, "number of migrations to revert")
+		_ = flags.Parse(args[1:])
+		if *steps < 1 {
+			log.Fatalf("Number of steps must be positive, got %d", *steps)
+		}
+
+		reverted, err := migrator.Down(*steps)
+		if err != nil {
+			log.Fatalf("Error reverting migrations: %v", err)
+		}
+		log.Printf("Reverted %d migrations", reverted)
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatalf("Error retrieving migration status: %v", err)
+		}
+		printMigrationStatus(statuses)
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+}
+
+func printMigrationStatus(statuses []migrations.Status) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		state, appliedAt := "pending", ""
+		if status.Applied {
+			state = "applied"
+			if !status.ChecksumMatch {
+				state = "applied (checksum mismatch)"
+			}
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(writer, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	_ = writer.Flush()
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"SWIFT-Remitly/internal/models"
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrAuditFailed is returned by Audited if the audit entry of a mutation cannot be recorded.
+var ErrAuditFailed = errors.New("audit entry cannot be recorded")
+
+// AuditResult describes a successful mutation recorded by Audited.
+type AuditResult struct {
+	// Status is the status of the response to the mutation
+	Status int
+	// SWIFTCodes are the SWIFT codes of the banks stored by the mutation, recorded with their data after it
+	SWIFTCodes []string
+}
+
+// Audited runs the mutation with a service bound to a transaction and appends its audit entry in the same transaction.
+// The bank with the SWIFT code of the entry, if set, is locked and recorded before the mutation, the banks reported
+// by the mutation are recorded after it. Entries without a SWIFT code get an entry for every reported bank.
+// If the mutation fails, it is rolled back and the entry is recorded with the status of its error.
+func (s *service) Audited(entry models.AuditEntry, mutation func(db Service) (AuditResult, error)) error {
+	var mutationErr error
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if entry.SWIFTCode != "" {
+			// the bank is locked, so concurrent requests cannot change it between the snapshot and the mutation
+			if err := tx.
+				Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("swift_code = ?", entry.SWIFTCode).
+				Find(&[]models.Bank{}).Error; err != nil {
+				return err
+			}
+			before, err := auditPayload(tx, entry.SWIFTCode)
+			if err != nil {
+				return err
+			}
+			entry.Before = before
+		}
+
+		result, err := mutation(&service{db: tx})
+		if err != nil {
+			mutationErr = err
+			return err
+		}
+
+		entry.Status = result.Status
+		entries := []models.AuditEntry{entry}
+		if entry.SWIFTCode != "" {
+			// the SWIFT code may be changed by the mutation, the entry keeps the requested one
+			if len(result.SWIFTCodes) > 0 {
+				if entries[0].After, err = auditPayload(tx, result.SWIFTCodes[0]); err != nil {
+					return err
+				}
+			}
+		} else if len(result.SWIFTCodes) > 0 {
+			entries = make([]models.AuditEntry, len(result.SWIFTCodes))
+			for i, swiftCode := range result.SWIFTCodes {
+				entries[i] = entry
+				entries[i].SWIFTCode = swiftCode
+				if entries[i].After, err = auditPayload(tx, swiftCode); err != nil {
+					return err
+				}
+			}
+		}
+		return tx.Create(&entries).Error
+	})
+
+	if mutationErr != nil {
+		entry.Status = models.MapErrorToStatusCode(mutationErr).Status
+		if err := s.AddAuditEntry(entry); err != nil {
+			return fmt.Errorf("%w: %w", ErrAuditFailed, err)
+		}
+		return mutationErr
+	}
+	if err != nil {
+		s.db.Logger.Error(context.Background(), "Error during recording audit entry: "+err.Error())
+		return fmt.Errorf("%w: %w", ErrAuditFailed, err)
+	}
+	return nil
+}
+
+// auditPayload returns the stored bank with the SWIFT code as recorded in the audit log, nil if it is not stored.
+func auditPayload(tx *gorm.DB, swiftCode string) (models.AuditPayload, error) {
+	var bank models.Bank
+	err := tx.
+		Preload("Address.Town").
+		Preload("CodeType").
+		Preload("Country").
+		Preload("Name").
+		Preload("TimeZone").
+		Where("swift_code = ?", swiftCode).
+		First(&bank).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return models.NewAuditPayload(bank)
+}
+
+// AddAuditEntry appends the entry to the audit log.
+func (s *service) AddAuditEntry(entry models.AuditEntry) error {
+	if err := s.db.Create(&entry).Error; err != nil {
+		s.db.Logger.Error(context.Background(), "Error during adding audit entry: "+err.Error())
+		return err
+	}
+	return nil
+}
+
+// GetAuditEntries retrieves the audit entries matching the query, newest first.
+func (s *service) GetAuditEntries(query models.AuditQuery) ([]models.AuditEntry, error) {
+	s.db.Logger.Info(context.Background(), "Retrieving audit entries from the database")
+
+	tx := s.db.Model(&models.AuditEntry{})
+	if query.SWIFTCode != "" {
+		tx = tx.Where("swift_code = ?", query.SWIFTCode)
+	}
+	if query.Actor != "" {
+		tx = tx.Where("actor = ?", query.Actor)
+	}
+	if !query.From.IsZero() {
+		tx = tx.Where("created_at >= ?", query.From)
+	}
+	if !query.To.IsZero() {
+		tx = tx.Where("created_at < ?", query.To)
+	}
+
+	entries := []models.AuditEntry{}
+	if err := tx.
+		Order("created_at DESC").
+		Order("id DESC").
+		Limit(query.Limit).
+		Find(&entries).Error; err != nil {
+		s.db.Logger.Error(context.Background(), "Error during retrieving audit entries: "+err.Error())
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,456 @@
+package database
+
+import (
+	"SWIFT-Remitly/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BulkMode controls how BulkUpsertBanks handles requests which cannot be stored.
+type BulkMode int
+
+const (
+	// BulkBestEffort stores all valid requests and reports the invalid ones.
+	BulkBestEffort BulkMode = iota
+	// BulkAtomic stores all requests or none of them.
+	BulkAtomic
+)
+
+// bulkBatchSize is the number of requests stored in a single batch.
+const bulkBatchSize = 500
+
+// ErrBulkAborted is returned by BulkUpsertBanks and AddBanksFromRequests in BulkAtomic mode if any request cannot be stored.
+var ErrBulkAborted = errors.New("bulk operation aborted, no bank data was stored")
+
+// ParseBulkMode converts the mode name, e.g. from an environment variable, to a BulkMode.
+func ParseBulkMode(name string) (BulkMode, error) {
+	switch strings.ToLower(name) {
+	case "best-effort":
+		return BulkBestEffort, nil
+	case "atomic":
+		return BulkAtomic, nil
+	default:
+		return 0, fmt.Errorf("unknown bulk import mode %q, expected best-effort or atomic", name)
+	}
+}
+
+// BulkResult is the outcome of storing a single request of a bulk upsert.
+type BulkResult struct {
+	Result models.UpsertResult
+	Err    error
+}
+
+type addressKey struct {
+	address string
+	townID  uint
+}
+
+// lookupCache holds the IDs of the lookup table records by their values.
+type lookupCache struct {
+	timeZones map[string]uint
+	countries map[string]uint
+	names     map[string]uint
+	codeTypes map[string]uint
+	towns     map[string]uint
+	addresses map[addressKey]uint
+}
+
+// loadLookupCache reads all lookup table records.
+func loadLookupCache(tx *gorm.DB) (*lookupCache, error) {
+	cache := &lookupCache{
+		timeZones: map[string]uint{},
+		countries: map[string]uint{},
+		names:     map[string]uint{},
+		codeTypes: map[string]uint{},
+		towns:     map[string]uint{},
+		addresses: map[addressKey]uint{},
+	}
+
+	var timeZones []models.TimeZone
+	var countries []models.BankCountry
+	var names []models.BankName
+	var codeTypes []models.CodeType
+	var towns []models.BankTown
+	var addresses []models.BankAddress
+	for _, records := range []interface{}{&timeZones, &countries, &names, &codeTypes, &towns, &addresses} {
+		if err := tx.Find(records).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	for _, timeZone := range timeZones {
+		cache.timeZones[timeZone.TimeZone] = timeZone.ID
+	}
+	for _, country := range countries {
+		cache.countries[country.ISO2Code] = country.ID
+	}
+	for _, name := range names {
+		cache.names[name.Name] = name.ID
+	}
+	for _, codeType := range codeTypes {
+		cache.codeTypes[codeType.CodeType] = codeType.ID
+	}
+	for _, town := range towns {
+		cache.towns[town.Town] = town.ID
+	}
+	for _, address := range addresses {
+		cache.addresses[addressKey{address.Address, address.TownID}] = address.ID
+	}
+	return cache, nil
+}
+
+// createMissingLookups creates the lookup table records of the requests missing from the cache in batches
+// and adds their IDs to the cache. The requests must be valid with canonical country names,
+// as the create hooks are skipped.
+func createMissingLookups(tx *gorm.DB, cache *lookupCache, requests []models.CreateBankRequest) error {
+	var timeZones []models.TimeZone
+	var countries []models.BankCountry
+	var names []models.BankName
+	var codeTypes []models.CodeType
+	var towns []models.BankTown
+	seen := map[string]bool{}
+	addMissing := func(kind string, value string, ids map[string]uint) bool {
+		if _, ok := ids[value]; ok || seen[kind+"\x00"+value] {
+			return false
+		}
+		seen[kind+"\x00"+value] = true
+		return true
+	}
+
+	for _, request := range requests {
+		if request.TimeZone != "" && addMissing("time zone", request.TimeZone, cache.timeZones) {
+			timeZones = append(timeZones, models.TimeZone{TimeZone: request.TimeZone})
+		}
+		if addMissing("country", request.ISO2Code, cache.countries) {
+			countries = append(countries, models.BankCountry{ISO2Code: request.ISO2Code, CountryName: request.CountryName})
+		}
+		if addMissing("name", request.BankName, cache.names) {
+			names = append(names, models.BankName{Name: request.BankName})
+		}
+		if addMissing("code type", request.CodeType, cache.codeTypes) {
+			codeTypes = append(codeTypes, models.CodeType{CodeType: request.CodeType})
+		}
+		if addMissing("town", request.TownName, cache.towns) {
+			towns = append(towns, models.BankTown{Town: request.TownName})
+		}
+	}
+
+	if len(timeZones) > 0 {
+		if err := tx.CreateInBatches(&timeZones, bulkBatchSize).Error; err != nil {
+			return err
+		}
+		for _, timeZone := range timeZones {
+			cache.timeZones[timeZone.TimeZone] = timeZone.ID
+		}
+	}
+	if len(countries) > 0 {
+		if err := tx.CreateInBatches(&countries, bulkBatchSize).Error; err != nil {
+			return err
+		}
+		for _, country := range countries {
+			cache.countries[country.ISO2Code] = country.ID
+		}
+	}
+	if len(names) > 0 {
+		if err := tx.CreateInBatches(&names, bulkBatchSize).Error; err != nil {
+			return err
+		}
+		for _, name := range names {
+			cache.names[name.Name] = name.ID
+		}
+	}
+	if len(codeTypes) > 0 {
+		if err := tx.CreateInBatches(&codeTypes, bulkBatchSize).Error; err != nil {
+			return err
+		}
+		for _, codeType := range codeTypes {
+			cache.codeTypes[codeType.CodeType] = codeType.ID
+		}
+	}
+	if len(towns) > 0 {
+		if err := tx.CreateInBatches(&towns, bulkBatchSize).Error; err != nil {
+			return err
+		}
+		for _, town := range towns {
+			cache.towns[town.Town] = town.ID
+		}
+	}
+
+	// addresses reference towns, so they are created after the towns
+	var addresses []models.BankAddress
+	seenAddresses := map[addressKey]bool{}
+	for _, request := range requests {
+		key := addressKey{request.Address, cache.towns[request.TownName]}
+		if _, ok := cache.addresses[key]; ok || seenAddresses[key] {
+			continue
+		}
+		seenAddresses[key] = true
+		addresses = append(addresses, models.BankAddress{Address: key.address, TownID: key.townID})
+	}
+	if len(addresses) > 0 {
+		if err := tx.Omit(clause.Associations).CreateInBatches(&addresses, bulkBatchSize).Error; err != nil {
+			return err
+		}
+		for _, address := range addresses {
+			cache.addresses[addressKey{address.Address, address.TownID}] = address.ID
+		}
+	}
+	return nil
+}
+
+// bankFromCache returns a bank with SWIFT code and foreign keys of the request taken from the cache.
+func bankFromCache(cache *lookupCache, request models.CreateBankRequest) models.Bank {
+	bank := models.Bank{
+		SWIFTCode:  request.SWIFTCode,
+		CodeTypeID: cache.codeTypes[request.CodeType],
+		NameID:     cache.names[request.BankName],
+		AddressID:  cache.addresses[addressKey{request.Address, cache.towns[request.TownName]}],
+		CountryID:  cache.countries[request.ISO2Code],
+	}
+	if request.TimeZone != "" {
+		timeZoneID := cache.timeZones[request.TimeZone]
+		bank.TimeZoneID = &timeZoneID
+	}
+	return bank
+}
+
+// sameReferences reports whether both banks reference the same lookup table records.
+func sameReferences(first models.Bank, second models.Bank) bool {
+	sameTimeZone := (first.TimeZoneID == nil && second.TimeZoneID == nil) ||
+		(first.TimeZoneID != nil && second.TimeZoneID != nil && *first.TimeZoneID == *second.TimeZoneID)
+	return first.CodeTypeID == second.CodeTypeID &&
+		first.NameID == second.NameID &&
+		first.AddressID == second.AddressID &&
+		first.CountryID == second.CountryID &&
+		sameTimeZone
+}
+
+// storeBankBatch inserts or updates the banks of the valid requests and records their changes, setting their results.
+// Headquarter links are not updated, see linkHeadquartersBulk.
+func storeBankBatch(tx *gorm.DB, cache *lookupCache, requests []models.CreateBankRequest, indexes []int, results []BulkResult) error {
+	if err := createMissingLookups(tx, cache, requests); err != nil {
+		return err
+	}
+
+	swiftCodes := make([]string, len(requests))
+	for i, request := range requests {
+		swiftCodes[i] = request.SWIFTCode
+	}
+	var existingBanks []models.Bank
+	if err := tx.Where("swift_code IN ?", swiftCodes).Find(&existingBanks).Error; err != nil {
+		return err
+	}
+	stored := make(map[string]*models.Bank, len(requests))
+	for i := range existingBanks {
+		stored[existingBanks[i].SWIFTCode] = &existingBanks[i]
+	}
+
+	var newBanks []*models.Bank
+	updatedBanks := map[string]models.Bank{}
+	var unusedNames, unusedAddresses []uint
+	for i, request := range requests {
+		bank := bankFromCache(cache, request)
+		current, ok := stored[request.SWIFTCode]
+		switch {
+		case !ok:
+			newBank := bank
+			newBanks = append(newBanks, &newBank)
+			stored[request.SWIFTCode] = &newBank
+			results[indexes[i]].Result = models.UpsertInserted
+		case sameReferences(*current, bank):
+			results[indexes[i]].Result = models.UpsertUnchanged
+		default:
+			if current.ID != 0 {
+				// the bank was stored before this batch, the records it no longer uses may become unused
+				if current.NameID != bank.NameID {
+					unusedNames = append(unusedNames, current.NameID)
+				}
+				if current.AddressID != bank.AddressID {
+					unusedAddresses = append(unusedAddresses, current.AddressID)
+				}
+				bank.ID = current.ID
+				updatedBanks[request.SWIFTCode] = bank
+			}
+			// a bank repeated in the requests is stored with its latest data
+			*current = bank
+			results[indexes[i]].Result = models.UpsertUpdated
+		}
+	}
+
+	if len(newBanks) > 0 {
+		if err := tx.Omit(clause.Associations).CreateInBatches(newBanks, bulkBatchSize).Error; err != nil {
+			return err
+		}
+		newBankIDs := make([]uint, len(newBanks))
+		for i, bank := range newBanks {
+			newBankIDs[i] = bank.ID
+		}
+		if err := recordChanges(tx, models.ChangeCreated, newBankIDs); err != nil {
+			return err
+		}
+	}
+	updatedBankIDs := make([]uint, 0, len(updatedBanks))
+	for _, bank := range updatedBanks {
+		updatedBankIDs = append(updatedBankIDs, bank.ID)
+		if err := tx.
+			Model(&models.Bank{}).
+			Where("id = ?", bank.ID).
+			Updates(map[string]interface{}{
+				"code_type_id": bank.CodeTypeID,
+				"name_id":      bank.NameID,
+				"address_id":   bank.AddressID,
+				"country_id":   bank.CountryID,
+				"time_zone_id": bank.TimeZoneID,
+			}).Error; err != nil {
+			return err
+		}
+	}
+	if err := recordChanges(tx, models.ChangeUpdated, updatedBankIDs); err != nil {
+		return err
+	}
+	return deleteUnusedNamesAndAddresses(tx, cache, unusedNames, unusedAddresses)
+}
+
+// deleteUnusedNamesAndAddresses deletes the names and addresses, together with the towns of the addresses,
+// which are no longer used by any bank, and removes them from the cache.
+func deleteUnusedNamesAndAddresses(tx *gorm.DB, cache *lookupCache, nameIDs []uint, addressIDs []uint) error {
+	if len(nameIDs) > 0 {
+		var deletedNames []models.BankName
+		if err := tx.Raw(
+			"DELETE FROM bank_names WHERE id IN ? AND NOT EXISTS (SELECT 1 FROM banks WHERE banks.name_id = bank_names.id) RETURNING *",
+			nameIDs).Scan(&deletedNames).Error; err != nil {
+			return err
+		}
+		for _, name := range deletedNames {
+			delete(cache.names, name.Name)
+		}
+	}
+	if len(addressIDs) > 0 {
+		var deletedAddresses []models.BankAddress
+		if err := tx.Raw(
+			"DELETE FROM bank_addresses WHERE id IN ? AND NOT EXISTS (SELECT 1 FROM banks WHERE banks.address_id = bank_addresses.id) RETURNING *",
+			addressIDs).Scan(&deletedAddresses).Error; err != nil {
+			return err
+		}
+		if len(deletedAddresses) == 0 {
+			return nil
+		}
+
+		townIDs := make([]uint, len(deletedAddresses))
+		for i, address := range deletedAddresses {
+			delete(cache.addresses, addressKey{address.Address, address.TownID})
+			townIDs[i] = address.TownID
+		}
+		var deletedTowns []models.BankTown
+		if err := tx.Raw(
+			"DELETE FROM bank_towns WHERE id IN ? AND NOT EXISTS (SELECT 1 FROM bank_addresses WHERE bank_addresses.town_id = bank_towns.id) RETURNING *",
+			townIDs).Scan(&deletedTowns).Error; err != nil {
+			return err
+		}
+		for _, town := range deletedTowns {
+			delete(cache.towns, town.Town)
+		}
+	}
+	return nil
+}
+
+// linkHeadquartersBulk links all branches to their headquarters in a single statement,
+// based on the first 8 characters of the SWIFT code. Deleted banks are not linked.
+func linkHeadquartersBulk(tx *gorm.DB) error {
+	return tx.Exec(`UPDATE banks AS branch SET headquarter_id = headquarter.id
+		FROM banks AS headquarter
+		WHERE branch.swift_code NOT LIKE '%XXX'
+		AND headquarter.swift_code = LEFT(branch.swift_code, 8) || 'XXX'
+		AND branch.deleted_at IS NULL AND headquarter.deleted_at IS NULL
+		AND branch.headquarter_id IS DISTINCT FROM headquarter.id`).Error
+}
+
+// BulkUpsertBanks adds or updates the bank data of all requests, matching banks by their SWIFT codes.
+// Lookup table IDs are cached in memory, banks are stored in batches and headquarter links are resolved
+// in a single pass at the end, so the order of headquarters and branches in the requests does not matter.
+// It returns the result of every request, in BulkAtomic mode wrapping ErrBulkAborted if any request cannot be stored.
+func (s *service) BulkUpsertBanks(requests []models.CreateBankRequest, mode BulkMode) ([]BulkResult, error) {
+	s.db.Logger.Info(s.db.Statement.Context, fmt.Sprintf("Bulk upserting %d banks in the database", len(requests)))
+
+	results := make([]BulkResult, len(requests))
+	validRequests := make([]models.CreateBankRequest, 0, len(requests))
+	validIndexes := make([]int, 0, len(requests))
+	for i, request := range requests {
+		request.Normalize()
+		if err := request.ValidateForStorage(); err != nil {
+			results[i].Err = err
+			continue
+		}
+		if countryName, err := models.CanonicalCountryName(request.ISO2Code, request.CountryName); err == nil {
+			request.CountryName = countryName
+		}
+		validRequests = append(validRequests, request)
+		validIndexes = append(validIndexes, i)
+	}
+
+	// hooks validate and link banks one by one, the requests are validated above and linked in bulk instead
+	db := s.db.Session(&gorm.Session{SkipHooks: true})
+
+	if mode == BulkAtomic {
+		if len(validRequests) != len(requests) {
+			return results, fmt.Errorf("%w: %d of %d rows are invalid", ErrBulkAborted, len(requests)-len(validRequests), len(requests))
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			cache, err := loadLookupCache(tx)
+			if err != nil {
+				return err
+			}
+			for start := 0; start < len(validRequests); start += bulkBatchSize {
+				end := min(start+bulkBatchSize, len(validRequests))
+				if err := storeBankBatch(tx, cache, validRequests[start:end], validIndexes[start:end], results); err != nil {
+					return err
+				}
+			}
+			return linkHeadquartersBulk(tx)
+		})
+		if err != nil {
+			s.db.Logger.Error(s.db.Statement.Context, "Error during bulk upserting banks: "+err.Error())
+			for i := range results {
+				results[i] = BulkResult{Err: err}
+			}
+			return results, fmt.Errorf("%w: %w", ErrBulkAborted, err)
+		}
+		return results, nil
+	}
+
+	cache, err := loadLookupCache(db)
+	if err != nil {
+		s.db.Logger.Error(s.db.Statement.Context, "Error during bulk upserting banks: "+err.Error())
+		return results, err
+	}
+	for start := 0; start < len(validRequests); start += bulkBatchSize {
+		end := min(start+bulkBatchSize, len(validRequests))
+		batch, indexes := validRequests[start:end], validIndexes[start:end]
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return storeBankBatch(tx, cache, batch, indexes, results)
+		}); err == nil {
+			continue
+		} else {
+			s.db.Logger.Warn(s.db.Statement.Context, "Bulk upserting batch failed, storing banks one by one: "+err.Error())
+		}
+
+		// the failed batch is stored row by row to find the requests which cannot be stored
+		for i, request := range batch {
+			results[indexes[i]].Result, results[indexes[i]].Err = s.UpsertBankFromRequest(request)
+		}
+		if cache, err = loadLookupCache(db); err != nil {
+			s.db.Logger.Error(s.db.Statement.Context, "Error during bulk upserting banks: "+err.Error())
+			return results, err
+		}
+	}
+
+	if err := linkHeadquartersBulk(db); err != nil {
+		s.db.Logger.Error(s.db.Statement.Context, "Error during linking headquarters: "+err.Error())
+		return results, err
+	}
+	return results, nil
+}
@@ -1,8 +1,10 @@
 package database
 
 import (
+	"SWIFT-Remitly/internal/database/migrations"
 	"SWIFT-Remitly/internal/models"
 	"context"
+	"errors"
 	"fmt"
 	_ "github.com/joho/godotenv/autoload"
 	"gorm.io/driver/postgres"
@@ -10,6 +12,7 @@ import (
 	"gorm.io/gorm/logger"
 	"log"
 	"os"
+	"time"
 )
 
 // Service represents a service that interacts with a database.
@@ -23,17 +26,96 @@ type Service interface {
 	// It returns the bank data and an error if the bank data cannot be retrieved.
 	GetBankBySwiftCode(swiftCode string) (models.Bank, error)
 
-	// GetBanksByISO2Code retrieves the bank data from the database based on the ISO2 code.
-	// It returns the bank data and an error if the bank data cannot be retrieved.
-	GetBanksByISO2Code(iso2Code string) (models.CountrySWIFTCode, error)
+	// GetBanksBySwiftCodes retrieves the bank data with all lookup table records of every stored SWIFT code of the list,
+	// headquarters together with their branches.
+	// It returns the banks by their SWIFT codes and an error if the bank data cannot be retrieved.
+	GetBanksBySwiftCodes(swiftCodes []string) (map[string]models.Bank, error)
+
+	// GetInstitution retrieves the headquarter and all branches whose SWIFT code starts with the institution code.
+	// It returns the banks and an error wrapping gorm.ErrRecordNotFound if no bank of the institution is stored.
+	GetInstitution(institutionCode string) (models.Institution, error)
+
+	// ExportBanks retrieves the bank data with all lookup table records ordered by SWIFT code
+	// and passes it to the function in batches. Banks of all countries are retrieved if the ISO2 code is empty.
+	// It returns an error if the country is not stored, the bank data cannot be retrieved or the function fails.
+	ExportBanks(iso2Code string, handleBatch func(banks []models.Bank) error) error
+
+	// GetBanksByISO2Code retrieves a page of the bank data from the database based on the ISO2 code.
+	// It returns the bank data with the cursor of the next page and an error if the bank data cannot be retrieved.
+	GetBanksByISO2Code(iso2Code string, page models.PageRequest) (models.CountrySWIFTCode, error)
 
 	// AddBankFromRequest adds the bank data to the database.
 	// It returns an error if the bank data cannot be added.
 	AddBankFromRequest(requestData models.CreateBankRequest) error
 
-	// DeleteBankBySwiftCode the bank data from the database based on the SWIFT code.
+	// AddBanksFromRequests adds the bank data of all requests, in BulkAtomic mode in a single transaction.
+	// It returns the result of every request, and an error wrapping ErrBulkAborted
+	// if the requests were not stored in BulkAtomic mode.
+	AddBanksFromRequests(requests []models.CreateBankRequest, mode BulkMode) ([]BulkResult, error)
+
+	// DeleteBankBySwiftCode soft deletes the bank data based on the SWIFT code, keeping it until it is purged.
 	// It returns an error if the bank data cannot be removed.
 	DeleteBankBySwiftCode(swiftCode string) error
+
+	// RestoreBankBySwiftCode restores the most recently deleted bank with the SWIFT code.
+	// It returns an error wrapping gorm.ErrRecordNotFound if no deleted bank has the SWIFT code,
+	// and gorm.ErrDuplicatedKey if another bank with the SWIFT code is stored.
+	RestoreBankBySwiftCode(swiftCode string) error
+
+	// GetDeletedBanks retrieves the deleted banks which are not purged yet, most recently deleted first.
+	// It returns the banks and an error if the bank data cannot be retrieved.
+	GetDeletedBanks() ([]models.Bank, error)
+
+	// PurgeDeletedBanks permanently deletes the banks deleted before the time.
+	// It returns the number of purged banks and an error if any bank cannot be purged.
+	PurgeDeletedBanks(deletedBefore time.Time) (int, error)
+
+	// UpdateBank updates the bank data in the database based on the SWIFT code.
+	// Fields of the request left nil keep their current values.
+	// It returns an error if the bank data cannot be updated.
+	UpdateBank(swiftCode string, requestData models.UpdateBankRequest) error
+
+	// UpsertBankFromRequest adds the bank data to the database or updates the bank with the same SWIFT code.
+	// It returns whether the bank was inserted, updated or left unchanged, and an error if the bank data cannot be stored.
+	UpsertBankFromRequest(requestData models.CreateBankRequest) (models.UpsertResult, error)
+
+	// BulkUpsertBanks adds or updates the bank data of all requests using batched inserts.
+	// It returns the result of every request, and an error wrapping ErrBulkAborted
+	// if the requests were not stored in BulkAtomic mode.
+	BulkUpsertBanks(requests []models.CreateBankRequest, mode BulkMode) ([]BulkResult, error)
+
+	// GetCountries retrieves all ISO 3166-1 countries together with the number of their banks.
+	// It returns the countries and an error if the bank counts cannot be retrieved.
+	GetCountries() ([]models.CountrySummary, error)
+
+	// GetBankHistory retrieves the changes of the banks stored under the SWIFT code, oldest first.
+	// It returns the history and an error wrapping gorm.ErrRecordNotFound if the SWIFT code has no changes.
+	GetBankHistory(swiftCode string) (models.BankHistory, error)
+
+	// GetBankAsOf retrieves the bank data the SWIFT code resolved to at the time, without branches.
+	// It returns the bank data and an error wrapping gorm.ErrRecordNotFound if no bank had the SWIFT code at the time.
+	GetBankAsOf(swiftCode string, asOf time.Time) (models.Bank, error)
+
+	// GetBanksAsOf retrieves the bank data every SWIFT code of the list resolved to at the time.
+	// It returns the banks by their SWIFT codes and an error if the history cannot be retrieved.
+	GetBanksAsOf(swiftCodes []string, asOf time.Time) (map[string]models.Bank, error)
+
+	// Audited runs the mutation in a transaction together with appending its audit entry to the audit log,
+	// with the bank of the SWIFT code of the entry before and the banks reported by the mutation after it.
+	// It returns the error of the mutation, and an error wrapping ErrAuditFailed if the entry cannot be recorded.
+	Audited(entry models.AuditEntry, mutation func(db Service) (AuditResult, error)) error
+
+	// AddAuditEntry appends the entry to the append-only audit log.
+	// It returns an error if the entry cannot be stored.
+	AddAuditEntry(entry models.AuditEntry) error
+
+	// GetAuditEntries retrieves the audit entries matching the query, newest first.
+	// It returns the entries and an error if the audit log cannot be read.
+	GetAuditEntries(query models.AuditQuery) ([]models.AuditEntry, error)
+
+	// SearchBanks retrieves the banks whose name, address or town matches the searched text, best matches first.
+	// It returns the ranked banks with the highlighted matches and an error if the banks cannot be searched.
+	SearchBanks(query models.SearchQuery) ([]models.SearchResult, error)
 }
 
 type service struct {
@@ -60,19 +142,7 @@ func New(dbIn *gorm.DB) Service {
 	if dbInstance != nil {
 		return dbInstance
 	}
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable search_path=%s", host, username, password, database, port, schema)
-	newLogger := logger.New(
-		log.New(os.Stdout, "\r\n", log.LstdFlags), // io writer)
-		logger.Config{
-			LogLevel:                  logger.Info,
-			IgnoreRecordNotFoundError: true,
-			Colorful:                  true,
-		})
-
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger:         newLogger,
-		TranslateError: true,
-	})
+	db, err := Connect()
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -87,22 +157,34 @@ func New(dbIn *gorm.DB) Service {
 	return dbInstance
 }
 
+// Connect opens a new connection to the database configured by the environment variables.
+// It returns the connection and an error if the connection cannot be opened.
+func Connect() (*gorm.DB, error) {
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable search_path=%s", host, username, password, database, port, schema)
+	newLogger := logger.New(
+		log.New(os.Stdout, "\r\n", log.LstdFlags), // io writer)
+		logger.Config{
+			LogLevel:                  logger.Info,
+			IgnoreRecordNotFoundError: true,
+			Colorful:                  true,
+		})
+
+	return gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger:         newLogger,
+		TranslateError: true,
+	})
+}
+
+// migrate applies pending schema migrations, keeping the data already stored in the database.
 func (s *service) migrate() error {
 	s.db.Logger.Info(context.Background(), "Migrating the database")
 
-	s.db.Logger.Info(context.Background(), "Dropping tables")
-	err := s.db.Migrator().DropTable(&models.TimeZone{}, &models.BankCountry{}, &models.BankName{}, &models.CodeType{}, &models.BankTown{}, &models.BankAddress{}, &models.Bank{})
+	applied, err := migrations.New(s.db).Up()
 	if err != nil {
-		s.db.Logger.Error(context.Background(), "Error during dropping tables: "+err.Error())
-		return err
-	}
-
-	s.db.Logger.Info(context.Background(), "Auto migrating tables")
-	err = s.db.AutoMigrate(&models.TimeZone{}, &models.BankCountry{}, &models.BankName{}, &models.CodeType{}, &models.BankTown{}, &models.BankAddress{}, &models.Bank{})
-	if err != nil {
-		s.db.Logger.Error(context.Background(), "Error during auto migrating tables: "+err.Error())
+		s.db.Logger.Error(context.Background(), "Error during migrating the database: "+err.Error())
 		return err
 	}
+	s.db.Logger.Info(context.Background(), fmt.Sprintf("Applied %d migrations", applied))
 	return nil
 }
 
@@ -120,8 +202,23 @@ func (s *service) Close() error {
 	return sqlDB.Close()
 }
 
-// GetBanksByISO2Code retrieves the banks data from the database based on the ISO2 code.
-func (s *service) GetBanksByISO2Code(iso2Code string) (models.CountrySWIFTCode, error) {
+// bankSortColumns are the joins and columns ordering the banks of a country listing.
+var bankSortColumns = map[models.BankSort]struct {
+	joins  string
+	column string
+}{
+	models.SortBySWIFTCode: {column: "banks.swift_code"},
+	models.SortByBankName:  {joins: "JOIN bank_names ON bank_names.id = banks.name_id", column: "bank_names.name"},
+	models.SortByTown: {
+		joins:  "JOIN bank_addresses ON bank_addresses.id = banks.address_id JOIN bank_towns ON bank_towns.id = bank_addresses.town_id",
+		column: "bank_towns.town",
+	},
+}
+
+// GetBanksByISO2Code retrieves a page of the banks data from the database based on the ISO2 code.
+// Banks are ordered by the sorted column and then by SWIFT code, the next page starts after the cursor,
+// so pages stay consistent when banks are added or removed between requests.
+func (s *service) GetBanksByISO2Code(iso2Code string, page models.PageRequest) (models.CountrySWIFTCode, error) {
 	s.db.Logger.Info(context.Background(), "Retrieving banks data from the database by ISO2 code")
 
 	bankCountry, err := s.getCountryByISO2Code(iso2Code)
@@ -130,16 +227,45 @@ func (s *service) GetBanksByISO2Code(iso2Code string) (models.CountrySWIFTCode,
 		return models.CountrySWIFTCode{}, err
 	}
 
-	var banks []models.Bank
+	if page.Sort == "" {
+		page.Sort = models.SortBySWIFTCode
+	}
+	sortColumns, ok := bankSortColumns[page.Sort]
+	if !ok {
+		return models.CountrySWIFTCode{}, &models.ErrRequestInvalid{Message: "Request invalid", Details: []string{"unknown sort order " + string(page.Sort)}}
+	}
 
-	if err := s.db.
+	query := s.db.
+		Select("banks.*").
 		Preload("Name").
-		Preload("Address").
+		Preload("Address.Town").
+		Preload("CodeType").
+		Preload("TimeZone").
 		Preload("Country", func(db *gorm.DB) *gorm.DB {
 			return db.Select("id, iso2_code")
 		}).
-		Where("country_id = ?", bankCountry.ID).
-		Find(&banks).Error; err != nil {
+		Where("banks.country_id = ?", bankCountry.ID)
+	if sortColumns.joins != "" {
+		query = query.Joins(sortColumns.joins)
+	}
+	if page.After != nil {
+		if page.Sort == models.SortBySWIFTCode {
+			query = query.Where("banks.swift_code > ?", page.After.SWIFTCode)
+		} else {
+			query = query.Where("("+sortColumns.column+", banks.swift_code) > (?, ?)", page.After.Value, page.After.SWIFTCode)
+		}
+	}
+	query = query.Order(sortColumns.column)
+	if page.Sort != models.SortBySWIFTCode {
+		query = query.Order("banks.swift_code")
+	}
+	// one more bank than requested tells whether there is a next page
+	if page.Limit > 0 {
+		query = query.Limit(page.Limit + 1)
+	}
+
+	var banks []models.Bank
+	if err := query.Find(&banks).Error; err != nil {
 		s.db.Logger.Error(context.Background(), "Error during retrieving banks by ISO2 code: "+err.Error())
 		return models.CountrySWIFTCode{}, err
 	}
@@ -147,11 +273,48 @@ func (s *service) GetBanksByISO2Code(iso2Code string) (models.CountrySWIFTCode,
 	var country models.CountrySWIFTCode
 	country.ISO2Code = bankCountry.ISO2Code
 	country.Country = bankCountry.CountryName
+	if page.Limit > 0 && len(banks) > page.Limit {
+		banks = banks[:page.Limit]
+		last := banks[len(banks)-1]
+		country.NextCursor = models.PageCursor{Sort: page.Sort, Value: page.Sort.SortValue(last), SWIFTCode: last.SWIFTCode}.Encode()
+	}
 	country.Banks = banks
 
 	return country, nil
 }
 
+// GetCountries retrieves all ISO 3166-1 countries together with the number of their banks.
+// Countries stored in the database but missing from the registry are appended at the end.
+func (s *service) GetCountries() ([]models.CountrySummary, error) {
+	s.db.Logger.Info(context.Background(), "Retrieving countries with bank counts from the database")
+
+	var storedCountries []models.CountrySummary
+	if err := s.db.
+		Model(&models.BankCountry{}).
+		Select("bank_countries.iso2_code, bank_countries.country_name AS country, COUNT(banks.id) AS bank_count").
+		Joins("LEFT JOIN banks ON banks.country_id = bank_countries.id AND banks.deleted_at IS NULL").
+		Group("bank_countries.iso2_code, bank_countries.country_name").
+		Order("bank_countries.iso2_code").
+		Scan(&storedCountries).Error; err != nil {
+		s.db.Logger.Error(context.Background(), "Error during retrieving countries: "+err.Error())
+		return nil, err
+	}
+
+	countries := models.ListCountries()
+	indexes := make(map[string]int, len(countries))
+	for i, country := range countries {
+		indexes[country.ISO2Code] = i
+	}
+	for _, storedCountry := range storedCountries {
+		if i, ok := indexes[storedCountry.ISO2Code]; ok {
+			countries[i].BankCount += storedCountry.BankCount
+		} else {
+			countries = append(countries, storedCountry)
+		}
+	}
+	return countries, nil
+}
+
 // GetBankBySwiftCode retrieves the bank data from the database based on the SWIFT code.
 func (s *service) GetBankBySwiftCode(swiftCode string) (models.Bank, error) {
 	s.db.Logger.Info(context.Background(), "Retrieving bank data from the database by SWIFT code")
@@ -159,8 +322,10 @@ func (s *service) GetBankBySwiftCode(swiftCode string) (models.Bank, error) {
 	var bank models.Bank
 	if err := s.db.
 		Preload("Name").
-		Preload("Address").
+		Preload("Address.Town").
+		Preload("CodeType").
 		Preload("Country").
+		Preload("TimeZone").
 		Where("swift_code = ?", swiftCode).
 		First(&bank).Error; err != nil {
 		s.db.Logger.Error(context.Background(), "Error during retrieving bank by SWIFT code: "+err.Error())
@@ -178,118 +343,317 @@ func (s *service) GetBankBySwiftCode(swiftCode string) (models.Bank, error) {
 	return bank, nil
 }
 
+// GetBanksBySwiftCodes retrieves the banks with the SWIFT codes in batches, skipping codes which are not stored.
+// Branches of the headquarters of a batch are retrieved by a single query, like by GetBankBySwiftCode.
+func (s *service) GetBanksBySwiftCodes(swiftCodes []string) (map[string]models.Bank, error) {
+	s.db.Logger.Info(context.Background(), fmt.Sprintf("Retrieving data of %d banks from the database by SWIFT codes", len(swiftCodes)))
+
+	banks := make(map[string]models.Bank, len(swiftCodes))
+	for start := 0; start < len(swiftCodes); start += bulkBatchSize {
+		var batch []models.Bank
+		if err := s.db.
+			Preload("Address.Town").
+			Preload("CodeType").
+			Preload("Country").
+			Preload("Name").
+			Preload("TimeZone").
+			Where("swift_code IN ?", swiftCodes[start:min(start+bulkBatchSize, len(swiftCodes))]).
+			Find(&batch).Error; err != nil {
+			s.db.Logger.Error(context.Background(), "Error during retrieving banks by SWIFT codes: "+err.Error())
+			return nil, err
+		}
+
+		headquarters := map[uint]*models.Bank{}
+		for i := range batch {
+			if batch[i].IsHeadquarterBank() {
+				batch[i].Branches = []models.Bank{}
+				headquarters[batch[i].ID] = &batch[i]
+			}
+		}
+		if len(headquarters) > 0 {
+			headquarterIDs := make([]uint, 0, len(headquarters))
+			for id := range headquarters {
+				headquarterIDs = append(headquarterIDs, id)
+			}
+			var branches []models.Bank
+			if err := s.db.
+				Preload("Name").
+				Preload("Address.Town").
+				Preload("CodeType").
+				Preload("TimeZone").
+				Preload("Country", func(db *gorm.DB) *gorm.DB {
+					return db.Select("id, iso2_code")
+				}).
+				Where("headquarter_id IN ?", headquarterIDs).
+				Find(&branches).Error; err != nil {
+				s.db.Logger.Error(context.Background(), "Error during retrieving branches of headquarters banks: "+err.Error())
+				return nil, err
+			}
+			for _, branch := range branches {
+				headquarter := headquarters[*branch.HeadquarterID]
+				headquarter.Branches = append(headquarter.Branches, branch)
+			}
+		}
+
+		for _, bank := range batch {
+			banks[bank.SWIFTCode] = bank
+		}
+	}
+	return banks, nil
+}
+
+// GetInstitution retrieves the banks of the institution ordered by SWIFT code, branches without a linked
+// headquarter included, so branches imported before their headquarter are listed too.
+func (s *service) GetInstitution(institutionCode string) (models.Institution, error) {
+	s.db.Logger.Info(context.Background(), "Retrieving institution banks data from the database by institution code")
+
+	var banks []models.Bank
+	if err := s.db.
+		Preload("Name").
+		Preload("Address.Town").
+		Preload("CodeType").
+		Preload("Country").
+		Preload("TimeZone").
+		Scopes(models.SameInstitution(institutionCode)).
+		Order("swift_code").
+		Find(&banks).Error; err != nil {
+		s.db.Logger.Error(context.Background(), "Error during retrieving institution banks: "+err.Error())
+		return models.Institution{}, err
+	}
+	if len(banks) == 0 {
+		return models.Institution{}, fmt.Errorf("institution %s: %w", institutionCode, gorm.ErrRecordNotFound)
+	}
+
+	institution := models.Institution{InstitutionCode: institutionCode, Branches: []models.Bank{}}
+	for i := range banks {
+		if banks[i].IsHeadquarterBank() {
+			institution.Headquarter = &banks[i]
+			continue
+		}
+		institution.Branches = append(institution.Branches, banks[i])
+	}
+	return institution, nil
+}
+
+// ExportBanks retrieves the banks in batches using the SWIFT code of the last bank of a batch as the cursor,
+// so no bank is read twice even if the function takes long.
+func (s *service) ExportBanks(iso2Code string, handleBatch func(banks []models.Bank) error) error {
+	s.db.Logger.Info(context.Background(), "Exporting banks data from the database")
+
+	query := s.db.Model(&models.Bank{})
+	if iso2Code != "" {
+		country, err := s.getCountryByISO2Code(iso2Code)
+		if err != nil {
+			s.db.Logger.Error(context.Background(), "Error during retrieving country by ISO2 code: "+err.Error())
+			return err
+		}
+		query = query.Where("country_id = ?", country.ID)
+	}
+
+	lastSWIFTCode := ""
+	for {
+		var batch []models.Bank
+		if err := query.Session(&gorm.Session{}).
+			Preload("Address.Town").
+			Preload("CodeType").
+			Preload("Country").
+			Preload("Name").
+			Preload("TimeZone").
+			Where("swift_code > ?", lastSWIFTCode).
+			Order("swift_code").
+			Limit(bulkBatchSize).
+			Find(&batch).Error; err != nil {
+			s.db.Logger.Error(context.Background(), "Error during exporting banks: "+err.Error())
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := handleBatch(batch); err != nil {
+			return err
+		}
+		if len(batch) < bulkBatchSize {
+			return nil
+		}
+		lastSWIFTCode = batch[len(batch)-1].SWIFTCode
+	}
+}
+
 // AddBankFromRequest adds the bank data to the database.
 func (s *service) AddBankFromRequest(requestData models.CreateBankRequest) error {
+	requestData.Normalize()
+
 	return s.db.Transaction(func(tx *gorm.DB) error {
-		tx.Logger.Info(tx.Statement.Context, "Adding bank data to the database")
+		return addBank(tx, requestData)
+	})
+}
 
-		var timeZone models.TimeZone
-		if err := tx.
-			Where("time_zone = ?", requestData.TimeZone).
-			FirstOrCreate(&timeZone, models.TimeZone{TimeZone: requestData.TimeZone}).Error; err != nil {
-			tx.Logger.Error(tx.Statement.Context, "Error during adding bank: "+err.Error())
-			return err
+// AddBanksFromRequests adds the banks in the order of the requests, so branches may follow their headquarter.
+// In BulkAtomic mode every bank is added in a savepoint of a single transaction, so all failing requests
+// are reported before the transaction is rolled back. In BulkBestEffort mode every bank has its own transaction.
+func (s *service) AddBanksFromRequests(requests []models.CreateBankRequest, mode BulkMode) ([]BulkResult, error) {
+	s.db.Logger.Info(context.Background(), fmt.Sprintf("Adding data of %d banks to the database", len(requests)))
+
+	results := make([]BulkResult, len(requests))
+	addAll := func(tx *gorm.DB) error {
+		failed := 0
+		for i, requestData := range requests {
+			requestData.Normalize()
+			results[i].Err = tx.Transaction(func(tx *gorm.DB) error {
+				return addBank(tx, requestData)
+			})
+			if results[i].Err != nil {
+				failed++
+				continue
+			}
+			results[i].Result = models.UpsertInserted
 		}
+		if failed > 0 && mode == BulkAtomic {
+			return fmt.Errorf("%w: %d of %d banks cannot be added", ErrBulkAborted, failed, len(requests))
+		}
+		return nil
+	}
 
-		var country models.BankCountry
-		if err := tx.
-			Where("iso2_code = ? AND country_name = ?", requestData.ISO2Code, requestData.CountryName).
-			FirstOrCreate(&country, models.BankCountry{ISO2Code: requestData.ISO2Code, CountryName: requestData.CountryName}).Error; err != nil {
-			tx.Logger.Error(tx.Statement.Context, "Error during adding bank: "+err.Error())
-			return err
+	if mode != BulkAtomic {
+		return results, addAll(s.db)
+	}
+	if err := s.db.Transaction(addAll); err != nil {
+		if errors.Is(err, ErrBulkAborted) {
+			return results, err
 		}
+		s.db.Logger.Error(context.Background(), "Error during adding banks: "+err.Error())
+		return nil, err
+	}
+	return results, nil
+}
+
+// UpdateBank updates the bank data identified by the SWIFT code with the provided fields.
+// Lookup table records no longer used by any bank are removed and headquarter links are restored
+// if the SWIFT code changes.
+func (s *service) UpdateBank(swiftCode string, requestData models.UpdateBankRequest) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		tx.Logger.Info(tx.Statement.Context, "Updating bank data in the database")
 
-		var name models.BankName
+		var bank models.Bank
 		if err := tx.
-			Where("name = ?", requestData.BankName).
-			FirstOrCreate(&name, models.BankName{Name: requestData.BankName}).Error; err != nil {
-			tx.Logger.Error(tx.Statement.Context, "Error during adding bank: "+err.Error())
+			Preload("Address.Town").
+			Preload("CodeType").
+			Preload("Country").
+			Preload("Name").
+			Preload("TimeZone").
+			Where("swift_code = ?", swiftCode).
+			First(&bank).Error; err != nil {
+			tx.Logger.Error(tx.Statement.Context, "Error during updating bank: "+err.Error())
 			return err
 		}
 
-		var codeType models.CodeType
-		if err := tx.
-			Where("code_type = ?", requestData.CodeType).
-			FirstOrCreate(&codeType, models.CodeType{CodeType: requestData.CodeType}).Error; err != nil {
-			tx.Logger.Error(tx.Statement.Context, "Error during adding bank: "+err.Error())
+		updatedData := requestData.ApplyTo(bank.ToCreateBankRequest())
+		if err := requestData.Validate(updatedData); err != nil {
+			tx.Logger.Error(tx.Statement.Context, "Error during updating bank: "+err.Error())
 			return err
 		}
 
-		var town models.BankTown
-		if err := tx.
-			Where("town = ?", requestData.TownName).
-			FirstOrCreate(&town, models.BankTown{Town: requestData.TownName}).Error; err != nil {
-			tx.Logger.Error(tx.Statement.Context, "Error during adding bank: "+err.Error())
+		if err := updateBankData(tx, bank, updatedData); err != nil {
+			tx.Logger.Error(tx.Statement.Context, "Error during updating bank: "+err.Error())
 			return err
 		}
 
-		var address models.BankAddress
-		if err := tx.
-			Where("address = ? AND town_id = ?", requestData.Address, town.ID).
-			FirstOrCreate(&address, models.BankAddress{Address: requestData.Address, TownID: town.ID}).Error; err != nil {
-			tx.Logger.Error(tx.Statement.Context, "Error during adding bank: "+err.Error())
+		return nil
+	})
+}
+
+// UpsertBankFromRequest adds the bank data to the database or updates the bank with the same SWIFT code.
+// Banks whose stored data is identical to the request are left untouched.
+func (s *service) UpsertBankFromRequest(requestData models.CreateBankRequest) (models.UpsertResult, error) {
+	requestData.Normalize()
+
+	var result models.UpsertResult
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		tx.Logger.Info(tx.Statement.Context, "Upserting bank data in the database")
+
+		var bank models.Bank
+		err := tx.
+			Preload("Address.Town").
+			Preload("CodeType").
+			Preload("Country").
+			Preload("Name").
+			Preload("TimeZone").
+			Where("swift_code = ?", requestData.SWIFTCode).
+			First(&bank).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			newBank, err := resolveBankReferences(tx, requestData)
+			if err != nil {
+				tx.Logger.Error(tx.Statement.Context, "Error during upserting bank: "+err.Error())
+				return err
+			}
+			if err := tx.Create(&newBank).Error; err != nil {
+				tx.Logger.Error(tx.Statement.Context, "Error during upserting bank: "+err.Error())
+				return err
+			}
+			if err := recordChanges(tx, models.ChangeCreated, []uint{newBank.ID}); err != nil {
+				return err
+			}
+			result = models.UpsertInserted
+			return nil
+		}
+		if err != nil {
+			tx.Logger.Error(tx.Statement.Context, "Error during upserting bank: "+err.Error())
 			return err
 		}
 
-		bank := models.Bank{
-			SWIFTCode:  requestData.SWIFTCode,
-			CodeTypeID: codeType.ID,
-			NameID:     name.ID,
-			AddressID:  address.ID,
-			CountryID:  country.ID,
-			TimeZoneID: timeZone.ID,
+		// stored country names are canonical, the request may use an alias
+		if countryName, err := models.CanonicalCountryName(requestData.ISO2Code, requestData.CountryName); err == nil {
+			requestData.CountryName = countryName
+		}
+		if requestData.SameBankData(bank.ToCreateBankRequest()) {
+			result = models.UpsertUnchanged
+			return nil
 		}
 
-		if err := tx.
-			Create(&bank).Error; err != nil {
-			tx.Logger.Error(tx.Statement.Context, "Error during adding bank: "+err.Error())
+		// the SWIFT code is checked by the create hook of new banks only
+		if err := models.ValidateSWIFTCountry(requestData.SWIFTCode, requestData.ISO2Code); err != nil {
+			tx.Logger.Error(tx.Statement.Context, "Error during upserting bank: "+err.Error())
 			return err
 		}
-
+		if err := updateBankData(tx, bank, requestData); err != nil {
+			tx.Logger.Error(tx.Statement.Context, "Error during upserting bank: "+err.Error())
+			return err
+		}
+		result = models.UpsertUpdated
 		return nil
 	})
+	if err != nil {
+		return 0, err
+	}
+	return result, nil
 }
 
-// DeleteBankBySwiftCode deletes the bank data from the database based on the SWIFT code.
+// DeleteBankBySwiftCode soft deletes the bank data based on the SWIFT code. The deleted bank keeps its lookup table
+// records until it is purged, its headquarter links are removed and restored by RestoreBankBySwiftCode.
 func (s *service) DeleteBankBySwiftCode(swiftCode string) error {
-	/*
-		I don't like the way this is done, but I'm not sure how to fix it
-		https://gorm.io/docs/associations.html#Delete-Associations
-		tried this ^, but it didn't work, or I did something wrong
-		specifying CASCADE in models didn't work either
-	*/
 	return s.db.Transaction(func(tx *gorm.DB) error {
 		tx.Logger.Info(tx.Statement.Context, "Deleting bank data from the database")
 		var bank models.Bank
 		if err := tx.
-			Preload("Address").
-			Preload("CodeType").
-			Preload("Country").
-			Preload("Name").
-			Preload("TimeZone").
 			Where("swift_code = ?", swiftCode).
 			First(&bank).Error; err != nil {
 			tx.Logger.Error(tx.Statement.Context, "Error during deleting bank: "+err.Error())
 			return err
 		}
 
-		if err := tx.Unscoped().Delete(&bank).Error; err != nil {
+		// branches of a deleted headquarter are unlinked by its BeforeDelete hook
+		if err := tx.
+			Model(&bank).
+			Update("headquarter_id", nil).Error; err != nil {
 			tx.Logger.Error(tx.Statement.Context, "Error during deleting bank: "+err.Error())
 			return err
 		}
 
-		entities := []interface{}{&bank.Address, &bank.CodeType, &bank.Country, &bank.Name, &bank.TimeZone}
-		for _, entity := range entities {
-			if err := tx.Unscoped().Delete(entity).Error; err != nil {
-				if err := handleDeleteError(tx, err); err != nil {
-					tx.Logger.Error(
-						tx.Statement.Context,
-						fmt.Sprintf("Error during deleting bank, error with %s entity: %s", tx.Statement.Table, err.Error()))
-					return err
-				}
-			}
+		if err := tx.Delete(&bank).Error; err != nil {
+			tx.Logger.Error(tx.Statement.Context, "Error during deleting bank: "+err.Error())
+			return err
 		}
 
-		return nil
+		return recordChanges(tx, models.ChangeDeleted, []uint{bank.ID})
 	})
 }
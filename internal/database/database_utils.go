@@ -4,6 +4,7 @@ import (
 	"SWIFT-Remitly/internal/models"
 	"context"
 	"errors"
+	"fmt"
 	"gorm.io/gorm"
 )
 
@@ -28,7 +29,9 @@ func (s *service) getHeadquarterBranches(headquarterID uint) ([]models.Bank, err
 	var banks []models.Bank
 	if err := s.db.
 		Preload("Name").
-		Preload("Address").
+		Preload("Address.Town").
+		Preload("CodeType").
+		Preload("TimeZone").
 		Preload("Country", func(db *gorm.DB) *gorm.DB {
 			return db.Select("id, iso2_code")
 		}).
@@ -40,6 +43,189 @@ func (s *service) getHeadquarterBranches(headquarterID uint) ([]models.Bank, err
 	return banks, nil
 }
 
+// addBank creates the bank of the request together with its missing lookup table records.
+func addBank(tx *gorm.DB, requestData models.CreateBankRequest) error {
+	tx.Logger.Info(tx.Statement.Context, "Adding bank data to the database")
+
+	bank, err := resolveBankReferences(tx, requestData)
+	if err != nil {
+		tx.Logger.Error(tx.Statement.Context, "Error during adding bank: "+err.Error())
+		return err
+	}
+
+	if err := tx.
+		Create(&bank).Error; err != nil {
+		tx.Logger.Error(tx.Statement.Context, "Error during adding bank: "+err.Error())
+		return err
+	}
+
+	return recordChanges(tx, models.ChangeCreated, []uint{bank.ID})
+}
+
+// resolveBankReferences finds or creates the lookup table records (time zone, country, name, code type, town and address)
+// of the request and returns a bank with SWIFT code and foreign keys set, ready to be created or updated.
+func resolveBankReferences(tx *gorm.DB, requestData models.CreateBankRequest) (models.Bank, error) {
+	// time zone is optional, bank without time zone is not linked to any time zone record
+	var timeZoneID *uint
+	if requestData.TimeZone != "" {
+		if err := models.ValidateTimeZoneCountry(requestData.TimeZone, requestData.ISO2Code); err != nil {
+			tx.Logger.Error(tx.Statement.Context, "Error during resolving bank references: "+err.Error())
+			return models.Bank{}, err
+		}
+
+		var timeZone models.TimeZone
+		if err := tx.
+			Where("time_zone = ?", requestData.TimeZone).
+			FirstOrCreate(&timeZone, models.TimeZone{TimeZone: requestData.TimeZone}).Error; err != nil {
+			tx.Logger.Error(tx.Statement.Context, "Error during resolving bank references: "+err.Error())
+			return models.Bank{}, err
+		}
+		timeZoneID = &timeZone.ID
+	} else {
+		tx.Logger.Warn(tx.Statement.Context, "Bank "+requestData.SWIFTCode+" has no time zone")
+	}
+
+	countryName, err := models.CanonicalCountryName(requestData.ISO2Code, requestData.CountryName)
+	if err != nil {
+		tx.Logger.Error(tx.Statement.Context, "Error during resolving bank references: "+err.Error())
+		return models.Bank{}, err
+	}
+
+	var country models.BankCountry
+	if err := tx.
+		Where("iso2_code = ?", requestData.ISO2Code).
+		FirstOrCreate(&country, models.BankCountry{ISO2Code: requestData.ISO2Code, CountryName: countryName}).Error; err != nil {
+		tx.Logger.Error(tx.Statement.Context, "Error during resolving bank references: "+err.Error())
+		return models.Bank{}, err
+	}
+
+	var name models.BankName
+	if err := tx.
+		Where("name = ?", requestData.BankName).
+		FirstOrCreate(&name, models.BankName{Name: requestData.BankName}).Error; err != nil {
+		tx.Logger.Error(tx.Statement.Context, "Error during resolving bank references: "+err.Error())
+		return models.Bank{}, err
+	}
+
+	var codeType models.CodeType
+	if err := tx.
+		Where("code_type = ?", requestData.CodeType).
+		FirstOrCreate(&codeType, models.CodeType{CodeType: requestData.CodeType}).Error; err != nil {
+		tx.Logger.Error(tx.Statement.Context, "Error during resolving bank references: "+err.Error())
+		return models.Bank{}, err
+	}
+
+	var town models.BankTown
+	if err := tx.
+		Where("town = ?", requestData.TownName).
+		FirstOrCreate(&town, models.BankTown{Town: requestData.TownName}).Error; err != nil {
+		tx.Logger.Error(tx.Statement.Context, "Error during resolving bank references: "+err.Error())
+		return models.Bank{}, err
+	}
+
+	var address models.BankAddress
+	if err := tx.
+		Where("address = ? AND town_id = ?", requestData.Address, town.ID).
+		FirstOrCreate(&address, models.BankAddress{Address: requestData.Address, TownID: town.ID}).Error; err != nil {
+		tx.Logger.Error(tx.Statement.Context, "Error during resolving bank references: "+err.Error())
+		return models.Bank{}, err
+	}
+
+	return models.Bank{
+		SWIFTCode:  requestData.SWIFTCode,
+		CodeTypeID: codeType.ID,
+		NameID:     name.ID,
+		AddressID:  address.ID,
+		CountryID:  country.ID,
+		TimeZoneID: timeZoneID,
+	}, nil
+}
+
+// updateBankData points the stored bank to the lookup table records of the updated data and records the change.
+// Headquarter links are restored if the SWIFT code changes, and name, address and town records
+// no longer used by any bank are removed.
+func updateBankData(tx *gorm.DB, bank models.Bank, updatedData models.CreateBankRequest) error {
+	updatedBank, err := resolveBankReferences(tx, updatedData)
+	if err != nil {
+		return err
+	}
+
+	// the old SWIFT code no longer resolves to the bank, its history ends with the data before the update
+	operation := models.ChangeUpdated
+	if updatedBank.SWIFTCode != bank.SWIFTCode {
+		if err := recordChanges(tx, models.ChangeDeleted, []uint{bank.ID}); err != nil {
+			return err
+		}
+		operation = models.ChangeCreated
+	}
+
+	if err := tx.
+		Model(&models.Bank{}).
+		Where("id = ?", bank.ID).
+		Updates(map[string]interface{}{
+			"swift_code":   updatedBank.SWIFTCode,
+			"code_type_id": updatedBank.CodeTypeID,
+			"name_id":      updatedBank.NameID,
+			"address_id":   updatedBank.AddressID,
+			"country_id":   updatedBank.CountryID,
+			"time_zone_id": updatedBank.TimeZoneID,
+		}).Error; err != nil {
+		return err
+	}
+	if err := recordChanges(tx, operation, []uint{bank.ID}); err != nil {
+		return err
+	}
+
+	if updatedBank.SWIFTCode != bank.SWIFTCode {
+		if err := relinkHeadquarter(tx, bank.ID); err != nil {
+			return err
+		}
+	}
+
+	var entities []interface{}
+	if updatedBank.NameID != bank.NameID {
+		entities = append(entities, &bank.Name)
+	}
+	if updatedBank.AddressID != bank.AddressID {
+		entities = append(entities, &bank.Address, &bank.Address.Town)
+	}
+	return deleteUnusedEntities(tx, entities)
+}
+
+// relinkHeadquarter removes headquarter links of the bank and its branches and links them again
+// based on the current SWIFT code of the bank.
+func relinkHeadquarter(tx *gorm.DB, bankID uint) error {
+	if err := tx.
+		Model(&models.Bank{}).
+		Where("headquarter_id = ? OR id = ?", bankID, bankID).
+		Update("headquarter_id", nil).Error; err != nil {
+		tx.Logger.Error(tx.Statement.Context, "Error during unlinking headquarter: "+err.Error())
+		return err
+	}
+
+	var bank models.Bank
+	if err := tx.Where("id = ?", bankID).First(&bank).Error; err != nil {
+		tx.Logger.Error(tx.Statement.Context, "Error during relinking headquarter: "+err.Error())
+		return err
+	}
+	return bank.LinkHeadquarter(tx)
+}
+
+// deleteUnusedEntities deletes the lookup table records, skipping the ones still in use.
+func deleteUnusedEntities(tx *gorm.DB, entities []interface{}) error {
+	for _, entity := range entities {
+		if err := tx.Unscoped().Delete(entity).Error; err != nil {
+			if err := handleDeleteError(tx, err); err != nil {
+				tx.Logger.Error(
+					tx.Statement.Context,
+					fmt.Sprintf("Error during deleting unused entity from %s: %s", tx.Statement.Table, err.Error()))
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // handleDeleteError handles the error returned when deleting an entity.
 func handleDeleteError(tx *gorm.DB, err error) error {
 	var inUseErr *models.ErrInUse
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"SWIFT-Remitly/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultRetention is how long deleted banks are kept before they are purged, if no retention period is configured.
+const DefaultRetention = 30 * 24 * time.Hour
+
+// ParseRetention converts the retention period of deleted banks, e.g. from an environment variable, to a duration.
+// An empty period means DefaultRetention.
+func ParseRetention(period string) (time.Duration, error) {
+	if period == "" {
+		return DefaultRetention, nil
+	}
+	retention, err := time.ParseDuration(period)
+	if err != nil || retention <= 0 {
+		return 0, fmt.Errorf("invalid retention period %q, expected a positive duration like 720h", period)
+	}
+	return retention, nil
+}
+
+// RestoreBankBySwiftCode restores the most recently deleted bank with the SWIFT code and links it again
+// with its headquarter or branches.
+func (s *service) RestoreBankBySwiftCode(swiftCode string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		tx.Logger.Info(tx.Statement.Context, "Restoring deleted bank data in the database")
+
+		var bank models.Bank
+		if err := tx.
+			Unscoped().
+			Where("swift_code = ? AND deleted_at IS NOT NULL", swiftCode).
+			Order("deleted_at DESC").
+			First(&bank).Error; err != nil {
+			tx.Logger.Error(tx.Statement.Context, "Error during restoring bank: "+err.Error())
+			return fmt.Errorf("deleted bank %s: %w", swiftCode, err)
+		}
+
+		// the SWIFT code may be used by a bank added after the deletion
+		err := tx.Where("swift_code = ?", swiftCode).First(&models.Bank{}).Error
+		if err == nil {
+			return fmt.Errorf("bank %s is stored: %w", swiftCode, gorm.ErrDuplicatedKey)
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			tx.Logger.Error(tx.Statement.Context, "Error during restoring bank: "+err.Error())
+			return err
+		}
+
+		if err := tx.
+			Unscoped().
+			Model(&bank).
+			Update("deleted_at", nil).Error; err != nil {
+			tx.Logger.Error(tx.Statement.Context, "Error during restoring bank: "+err.Error())
+			return err
+		}
+
+		if err := relinkHeadquarter(tx, bank.ID); err != nil {
+			return err
+		}
+		return recordChanges(tx, models.ChangeRestored, []uint{bank.ID})
+	})
+}
+
+// GetDeletedBanks retrieves the deleted banks together with their lookup table records.
+func (s *service) GetDeletedBanks() ([]models.Bank, error) {
+	s.db.Logger.Info(context.Background(), "Retrieving deleted banks data from the database")
+
+	var banks []models.Bank
+	if err := s.db.
+		Unscoped().
+		Preload("Address.Town").
+		Preload("CodeType").
+		Preload("Country").
+		Preload("Name").
+		Preload("TimeZone").
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC").
+		Order("swift_code").
+		Find(&banks).Error; err != nil {
+		s.db.Logger.Error(context.Background(), "Error during retrieving deleted banks: "+err.Error())
+		return nil, err
+	}
+	return banks, nil
+}
+
+// PurgeDeletedBanks permanently deletes the banks deleted before the time, every bank in its own transaction,
+// together with the lookup table records no longer used by any bank.
+func (s *service) PurgeDeletedBanks(deletedBefore time.Time) (int, error) {
+	s.db.Logger.Info(context.Background(), "Purging deleted banks data from the database")
+
+	var banks []models.Bank
+	if err := s.db.
+		Unscoped().
+		Preload("Address.Town").
+		Preload("CodeType").
+		Preload("Country").
+		Preload("Name").
+		Preload("TimeZone").
+		Where("deleted_at < ?", deletedBefore).
+		Find(&banks).Error; err != nil {
+		s.db.Logger.Error(context.Background(), "Error during purging deleted banks: "+err.Error())
+		return 0, err
+	}
+
+	for i := range banks {
+		if err := s.db.Transaction(func(tx *gorm.DB) error {
+			return purgeBank(tx, banks[i])
+		}); err != nil {
+			s.db.Logger.Error(context.Background(), "Error during purging deleted bank "+banks[i].SWIFTCode+": "+err.Error())
+			return i, err
+		}
+	}
+	return len(banks), nil
+}
+
+// purgeBank permanently deletes the bank with loaded associations and its lookup table records no longer in use.
+func purgeBank(tx *gorm.DB, bank models.Bank) error {
+	if err := tx.Unscoped().Delete(&bank).Error; err != nil {
+		return err
+	}
+
+	entities := []interface{}{&bank.Address, &bank.Address.Town, &bank.CodeType, &bank.Country, &bank.Name}
+	if bank.TimeZoneID != nil {
+		entities = append(entities, &bank.TimeZone)
+	}
+	return deleteUnusedEntities(tx, entities)
+}
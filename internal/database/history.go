@@ -0,0 +1,93 @@
+package database
+
+import (
+	"SWIFT-Remitly/internal/models"
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// recordChanges records the current data of the banks, deleted banks included, as changes of the operation.
+// The data is copied from the lookup table records by a single statement, so bulk changes are recorded at once.
+func recordChanges(tx *gorm.DB, operation models.ChangeOperation, bankIDs []uint) error {
+	if len(bankIDs) == 0 {
+		return nil
+	}
+	if err := tx.Exec(`INSERT INTO bank_changes
+		(bank_id, swift_code, operation, bank_name, address, town_name, iso2_code, country_name, code_type, time_zone)
+		SELECT banks.id, banks.swift_code, ?, bank_names.name, bank_addresses.address, bank_towns.town,
+			bank_countries.iso2_code, bank_countries.country_name, code_types.code_type, COALESCE(time_zones.time_zone, '')
+		FROM banks
+		JOIN bank_names ON bank_names.id = banks.name_id
+		JOIN bank_addresses ON bank_addresses.id = banks.address_id
+		JOIN bank_towns ON bank_towns.id = bank_addresses.town_id
+		JOIN bank_countries ON bank_countries.id = banks.country_id
+		JOIN code_types ON code_types.id = banks.code_type_id
+		LEFT JOIN time_zones ON time_zones.id = banks.time_zone_id
+		WHERE banks.id IN ?`, operation, bankIDs).Error; err != nil {
+		tx.Logger.Error(tx.Statement.Context, "Error during recording bank changes: "+err.Error())
+		return err
+	}
+	return nil
+}
+
+// GetBankHistory retrieves the changes of the banks stored under the SWIFT code, oldest first.
+func (s *service) GetBankHistory(swiftCode string) (models.BankHistory, error) {
+	s.db.Logger.Info(context.Background(), "Retrieving bank history from the database by SWIFT code")
+
+	var changes []models.BankChange
+	if err := s.db.
+		Where("swift_code = ?", swiftCode).
+		Order("changed_at").
+		Order("id").
+		Find(&changes).Error; err != nil {
+		s.db.Logger.Error(context.Background(), "Error during retrieving bank history: "+err.Error())
+		return models.BankHistory{}, err
+	}
+	if len(changes) == 0 {
+		return models.BankHistory{}, fmt.Errorf("history of bank %s: %w", swiftCode, gorm.ErrRecordNotFound)
+	}
+	return models.BankHistory{SWIFTCode: swiftCode, Changes: changes}, nil
+}
+
+// GetBankAsOf retrieves the bank data recorded by the latest change of the SWIFT code made until the time.
+func (s *service) GetBankAsOf(swiftCode string, asOf time.Time) (models.Bank, error) {
+	banks, err := s.GetBanksAsOf([]string{swiftCode}, asOf)
+	if err != nil {
+		return models.Bank{}, err
+	}
+	bank, ok := banks[swiftCode]
+	if !ok {
+		return models.Bank{}, fmt.Errorf("bank %s as of %s: %w", swiftCode, asOf.Format(time.RFC3339), gorm.ErrRecordNotFound)
+	}
+	return bank, nil
+}
+
+// GetBanksAsOf retrieves the latest changes of the SWIFT codes made until the time in batches, skipping codes
+// which were not stored or deleted at the time.
+func (s *service) GetBanksAsOf(swiftCodes []string, asOf time.Time) (map[string]models.Bank, error) {
+	s.db.Logger.Info(context.Background(), fmt.Sprintf("Retrieving history of %d banks from the database by SWIFT codes", len(swiftCodes)))
+
+	banks := make(map[string]models.Bank, len(swiftCodes))
+	for start := 0; start < len(swiftCodes); start += bulkBatchSize {
+		var batch []models.BankChange
+		if err := s.db.
+			Select("DISTINCT ON (swift_code) *").
+			Where("swift_code IN ? AND changed_at <= ?", swiftCodes[start:min(start+bulkBatchSize, len(swiftCodes))], asOf).
+			Order("swift_code").
+			Order("changed_at DESC").
+			Order("id DESC").
+			Find(&batch).Error; err != nil {
+			s.db.Logger.Error(context.Background(), "Error during retrieving banks as of a time: "+err.Error())
+			return nil, err
+		}
+		for _, change := range batch {
+			if change.Operation != models.ChangeDeleted {
+				banks[change.SWIFTCode] = change.ToBank()
+			}
+		}
+	}
+	return banks, nil
+}
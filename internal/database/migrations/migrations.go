@@ -0,0 +1,277 @@
+package migrations
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// sqlFiles holds migration scripts named <version>_<name>.up.sql and <version>_<name>.down.sql
+//
+//go:embed sql/*.sql
+var sqlFiles embed.FS
+
+// Migration is a single versioned schema change with scripts applying and reverting it.
+type Migration struct {
+	Version  uint
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Status describes a migration together with its state in the database.
+type Status struct {
+	Version   uint
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	// ChecksumMatch is false if the applied migration script differs from the one applied before
+	ChecksumMatch bool
+}
+
+// SchemaMigration is a record of the schema_migrations table.
+type SchemaMigration struct {
+	Version   uint   `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"not null"`
+	Checksum  string `gorm:"not null"`
+	AppliedAt time.Time
+}
+
+// Migrator applies and reverts the embedded migrations, recording them in the schema_migrations table.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// New creates a migrator using the migrations embedded in the application.
+// It panics if the embedded migration scripts are malformed.
+func New(db *gorm.DB) *Migrator {
+	migrations, err := load(sqlFiles)
+	if err != nil {
+		panic(err)
+	}
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// load reads the migration scripts and returns migrations ordered by version.
+func load(files fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration scripts: %w", err)
+	}
+
+	byVersion := make(map[uint]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		base, direction, found := strings.Cut(strings.TrimSuffix(fileName, ".sql"), ".")
+		if !found || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration script %s must end with .up.sql or .down.sql", fileName)
+		}
+		versionText, name, found := strings.Cut(base, "_")
+		if !found {
+			return nil, fmt.Errorf("migration script %s must be named <version>_<name>", fileName)
+		}
+		version, err := strconv.ParseUint(versionText, 10, 32)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("migration script %s has invalid version", fileName)
+		}
+
+		content, err := fs.ReadFile(files, path.Join("sql", fileName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration script %s: %w", fileName, err)
+		}
+
+		migration, ok := byVersion[uint(version)]
+		if !ok {
+			migration = &Migration{Version: uint(version), Name: name}
+			byVersion[uint(version)] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("migration %d has scripts with different names: %s and %s", version, migration.Name, name)
+		}
+		if direction == "up" {
+			migration.Up = string(content)
+			sum := sha256.Sum256(content)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down scripts", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// applied creates the schema_migrations table if needed and returns applied migrations by version.
+// It returns an error if an applied migration is unknown or its checksum does not match.
+func (m *Migrator) applied(verify bool) (map[uint]SchemaMigration, error) {
+	if err := m.db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var records []SchemaMigration
+	if err := m.db.Order("version").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	known := make(map[uint]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	applied := make(map[uint]SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+		if !verify {
+			continue
+		}
+		migration, ok := known[record.Version]
+		if !ok {
+			return nil, fmt.Errorf("applied migration %d_%s is unknown to the application", record.Version, record.Name)
+		}
+		if migration.Checksum != record.Checksum {
+			return nil, fmt.Errorf("checksum of applied migration %d_%s does not match its script", record.Version, record.Name)
+		}
+	}
+	return applied, nil
+}
+
+// Up applies all pending migrations in order, each in its own transaction.
+// It returns the number of applied migrations.
+func (m *Migrator) Up() (int, error) {
+	applied, err := m.applied(true)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		m.db.Logger.Info(m.db.Statement.Context, fmt.Sprintf("Applying migration %d_%s", migration.Version, migration.Name))
+
+		if err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				Checksum:  migration.Checksum,
+				AppliedAt: time.Now(),
+			}).Error
+		}); err != nil {
+			return count, fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Down reverts the given number of most recently applied migrations, each in its own transaction.
+// It returns the number of reverted migrations.
+func (m *Migrator) Down(steps int) (int, error) {
+	applied, err := m.applied(true)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(m.migrations) - 1; i >= 0 && count < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		m.db.Logger.Info(m.db.Statement.Context, fmt.Sprintf("Reverting migration %d_%s", migration.Version, migration.Name))
+
+		if err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, migration.Version).Error
+		}); err != nil {
+			return count, fmt.Errorf("failed to revert migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Reset reverts all applied migrations.
+func (m *Migrator) Reset() error {
+	_, err := m.Down(len(m.migrations))
+	return err
+}
+
+// Pending returns the migrations which are not applied yet in order, without modifying the database.
+func (m *Migrator) Pending() ([]Migration, error) {
+	if !m.db.Migrator().HasTable(&SchemaMigration{}) {
+		return m.migrations, nil
+	}
+
+	var records []SchemaMigration
+	if err := m.db.Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	applied := make(map[uint]bool, len(records))
+	for _, record := range records {
+		applied[record.Version] = true
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if !applied[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Status returns all known migrations with their state, without verifying checksums.
+// Applied migrations unknown to the application are appended at the end with a checksum mismatch.
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied(false)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &record.AppliedAt
+			status.ChecksumMatch = record.Checksum == migration.Checksum
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	unknown := make([]Status, 0, len(applied))
+	for _, record := range applied {
+		unknown = append(unknown, Status{Version: record.Version, Name: record.Name, Applied: true, AppliedAt: &record.AppliedAt})
+	}
+	sort.Slice(unknown, func(i, j int) bool {
+		return unknown[i].Version < unknown[j].Version
+	})
+	return append(statuses, unknown...), nil
+}
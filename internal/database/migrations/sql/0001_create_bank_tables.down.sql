@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS banks;
+DROP TABLE IF EXISTS bank_addresses;
+DROP TABLE IF EXISTS bank_towns;
+DROP TABLE IF EXISTS code_types;
+DROP TABLE IF EXISTS bank_names;
+DROP TABLE IF EXISTS bank_countries;
+DROP TABLE IF EXISTS time_zones;
@@ -0,0 +1,79 @@
+-- Baseline schema of the bank tables. Statements are idempotent so databases created before versioned
+-- migrations were introduced are adopted without losing their data, and brought to the same schema as new databases.
+
+CREATE TABLE IF NOT EXISTS time_zones
+(
+    id        bigserial PRIMARY KEY,
+    time_zone text NOT NULL,
+    CONSTRAINT uni_time_zones_time_zone UNIQUE (time_zone)
+);
+
+CREATE TABLE IF NOT EXISTS bank_countries
+(
+    id           bigserial PRIMARY KEY,
+    iso2_code    text NOT NULL,
+    country_name text NOT NULL,
+    CONSTRAINT uni_bank_countries_iso2_code UNIQUE (iso2_code)
+);
+
+-- Databases created before versioned migrations have a unique index on the ISO2 code together with the country name
+-- instead of the ISO2 code alone. The constraint cannot be added if countries share an ISO2 code.
+DROP INDEX IF EXISTS idx_country_iso2_code;
+DO
+$$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'uni_bank_countries_iso2_code') THEN
+        ALTER TABLE bank_countries ADD CONSTRAINT uni_bank_countries_iso2_code UNIQUE (iso2_code);
+    END IF;
+END
+$$;
+
+CREATE TABLE IF NOT EXISTS bank_names
+(
+    id   bigserial PRIMARY KEY,
+    name text NOT NULL,
+    CONSTRAINT uni_bank_names_name UNIQUE (name)
+);
+
+CREATE TABLE IF NOT EXISTS code_types
+(
+    id        bigserial PRIMARY KEY,
+    code_type text NOT NULL,
+    CONSTRAINT uni_code_types_code_type UNIQUE (code_type)
+);
+
+CREATE TABLE IF NOT EXISTS bank_towns
+(
+    id   bigserial PRIMARY KEY,
+    town text NOT NULL,
+    CONSTRAINT uni_bank_towns_town UNIQUE (town)
+);
+
+CREATE TABLE IF NOT EXISTS bank_addresses
+(
+    id      bigserial PRIMARY KEY,
+    address text   NOT NULL,
+    town_id bigint NOT NULL,
+    CONSTRAINT fk_bank_addresses_town FOREIGN KEY (town_id) REFERENCES bank_towns (id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_address_town ON bank_addresses (address, town_id);
+
+CREATE TABLE IF NOT EXISTS banks
+(
+    id             bigserial PRIMARY KEY,
+    swift_code     text NOT NULL,
+    code_type_id   bigint,
+    name_id        bigint,
+    address_id     bigint,
+    country_id     bigint,
+    time_zone_id   bigint,
+    headquarter_id bigint,
+    CONSTRAINT uni_banks_swift_code UNIQUE (swift_code),
+    CONSTRAINT fk_banks_code_type FOREIGN KEY (code_type_id) REFERENCES code_types (id),
+    CONSTRAINT fk_banks_name FOREIGN KEY (name_id) REFERENCES bank_names (id),
+    CONSTRAINT fk_banks_address FOREIGN KEY (address_id) REFERENCES bank_addresses (id),
+    CONSTRAINT fk_banks_country FOREIGN KEY (country_id) REFERENCES bank_countries (id),
+    CONSTRAINT fk_banks_time_zone FOREIGN KEY (time_zone_id) REFERENCES time_zones (id),
+    CONSTRAINT fk_banks_headquarter FOREIGN KEY (headquarter_id) REFERENCES banks (id)
+);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_banks_country_swift_code;
@@ -0,0 +1,2 @@
+-- Country listings are paginated by SWIFT code, the index serves both the filter and the order.
+CREATE INDEX IF NOT EXISTS idx_banks_country_swift_code ON banks (country_id, swift_code);
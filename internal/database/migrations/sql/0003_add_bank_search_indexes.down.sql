@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_bank_towns_town_trgm;
+DROP INDEX IF EXISTS idx_bank_addresses_address_trgm;
+DROP INDEX IF EXISTS idx_bank_names_name_trgm;
+DROP EXTENSION IF EXISTS pg_trgm;
@@ -0,0 +1,6 @@
+-- Trigram indexes serve the similarity and substring matches of the bank search.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_bank_names_name_trgm ON bank_names USING gin (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_bank_addresses_address_trgm ON bank_addresses USING gin (address gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_bank_towns_town_trgm ON bank_towns USING gin (town gin_trgm_ops);
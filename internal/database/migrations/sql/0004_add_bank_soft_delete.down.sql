@@ -0,0 +1,7 @@
+-- Deleted banks cannot be kept without the deleted_at column, they are removed permanently.
+DELETE FROM banks WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_banks_deleted_at;
+DROP INDEX IF EXISTS idx_banks_swift_code_active;
+ALTER TABLE banks ADD CONSTRAINT uni_banks_swift_code UNIQUE (swift_code);
+ALTER TABLE banks DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,9 @@
+-- Deleted banks are kept as tombstones until they are purged, so they can be restored.
+ALTER TABLE banks ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+-- A SWIFT code may be used again by a new bank while a deleted bank with the same code is kept.
+ALTER TABLE banks DROP CONSTRAINT IF EXISTS uni_banks_swift_code;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_banks_swift_code_active ON banks (swift_code) WHERE deleted_at IS NULL;
+
+-- Listings and purges of deleted banks read only the tombstones.
+CREATE INDEX IF NOT EXISTS idx_banks_deleted_at ON banks (deleted_at) WHERE deleted_at IS NOT NULL;
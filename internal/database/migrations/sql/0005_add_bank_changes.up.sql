@@ -0,0 +1,34 @@
+-- Every change of a bank is recorded with its data denormalized, so the history is kept unchanged
+-- when lookup table records are updated or the bank is purged.
+CREATE TABLE IF NOT EXISTS bank_changes (
+    id           bigserial PRIMARY KEY,
+    bank_id      bigint      NOT NULL,
+    swift_code   text        NOT NULL,
+    operation    text        NOT NULL,
+    bank_name    text        NOT NULL,
+    address      text        NOT NULL,
+    town_name    text        NOT NULL,
+    iso2_code    text        NOT NULL,
+    country_name text        NOT NULL,
+    code_type    text        NOT NULL,
+    time_zone    text        NOT NULL DEFAULT '',
+    changed_at   timestamptz NOT NULL DEFAULT now()
+);
+
+-- History listings and point-in-time lookups read the latest changes of SWIFT codes.
+CREATE INDEX IF NOT EXISTS idx_bank_changes_swift_code_changed_at ON bank_changes (swift_code, changed_at);
+
+-- Banks stored before the history was recorded get their current data as the first change,
+-- so they can be looked up from now on.
+INSERT INTO bank_changes
+    (bank_id, swift_code, operation, bank_name, address, town_name, iso2_code, country_name, code_type, time_zone)
+SELECT banks.id, banks.swift_code, 'created', bank_names.name, bank_addresses.address, bank_towns.town,
+    bank_countries.iso2_code, bank_countries.country_name, code_types.code_type, COALESCE(time_zones.time_zone, '')
+FROM banks
+JOIN bank_names ON bank_names.id = banks.name_id
+JOIN bank_addresses ON bank_addresses.id = banks.address_id
+JOIN bank_towns ON bank_towns.id = bank_addresses.town_id
+JOIN bank_countries ON bank_countries.id = banks.country_id
+JOIN code_types ON code_types.id = banks.code_type_id
+LEFT JOIN time_zones ON time_zones.id = banks.time_zone_id
+WHERE banks.deleted_at IS NULL;
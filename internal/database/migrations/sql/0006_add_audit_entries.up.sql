@@ -0,0 +1,30 @@
+-- Mutating API requests are recorded together with the bank data before and after the request.
+CREATE TABLE IF NOT EXISTS audit_entries (
+    id          bigserial PRIMARY KEY,
+    created_at  timestamptz NOT NULL DEFAULT now(),
+    actor       text        NOT NULL,
+    remote_ip   text        NOT NULL,
+    request_id  text        NOT NULL,
+    action      text        NOT NULL,
+    swift_code  text        NOT NULL DEFAULT '',
+    status      integer     NOT NULL,
+    before      jsonb,
+    after       jsonb
+);
+
+-- Audit entries are append-only, changing or deleting them is rejected.
+CREATE OR REPLACE FUNCTION reject_audit_entry_change() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit entries are append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_entries_append_only ON audit_entries;
+CREATE TRIGGER audit_entries_append_only
+    BEFORE UPDATE OR DELETE ON audit_entries
+    FOR EACH ROW EXECUTE FUNCTION reject_audit_entry_change();
+
+-- Audit queries filter by SWIFT code or actor and list the newest entries first.
+CREATE INDEX IF NOT EXISTS idx_audit_entries_created_at ON audit_entries (created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_entries_swift_code_created_at ON audit_entries (swift_code, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_entries_actor_created_at ON audit_entries (actor, created_at);
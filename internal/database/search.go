@@ -0,0 +1,89 @@
+package database
+
+import (
+	"SWIFT-Remitly/internal/models"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// searchBanksQuery ranks the banks by the best word similarity of the searched text to their name, town and address,
+// preferring names over towns and towns over addresses. Banks containing the text are matched even if it is
+// too short for similarity matching. The <% operator and ILIKE use the trigram indexes.
+const searchBanksQuery = `
+SELECT banks.id,
+       GREATEST(
+           word_similarity(@text, bank_names.name),
+           word_similarity(@text, bank_towns.town) * 0.9,
+           word_similarity(@text, bank_addresses.address) * 0.8
+       ) AS rank
+FROM banks
+         JOIN bank_names ON bank_names.id = banks.name_id
+         JOIN bank_addresses ON bank_addresses.id = banks.address_id
+         JOIN bank_towns ON bank_towns.id = bank_addresses.town_id
+         JOIN bank_countries ON bank_countries.id = banks.country_id
+WHERE (@text <% bank_names.name OR @text <% bank_towns.town OR @text <% bank_addresses.address
+    OR bank_names.name ILIKE @pattern OR bank_towns.town ILIKE @pattern OR bank_addresses.address ILIKE @pattern)
+  AND (@iso2Code = '' OR bank_countries.iso2_code = @iso2Code)
+  AND (@town = '' OR upper(bank_towns.town) = upper(@town))
+  AND (NOT @headquarterOnly OR banks.swift_code LIKE '%XXX')
+  AND banks.deleted_at IS NULL
+ORDER BY rank DESC, banks.swift_code
+LIMIT @limit`
+
+// likePatternEscaper escapes the wildcards of LIKE patterns.
+var likePatternEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchBanks retrieves the banks best matching the searched text together with their lookup table records.
+func (s *service) SearchBanks(query models.SearchQuery) ([]models.SearchResult, error) {
+	s.db.Logger.Info(context.Background(), fmt.Sprintf("Searching banks in the database by %q", query.Text))
+
+	var ranks []struct {
+		ID   uint
+		Rank float64
+	}
+	if err := s.db.Raw(searchBanksQuery, map[string]interface{}{
+		"text":            query.Text,
+		"pattern":         "%" + likePatternEscaper.Replace(query.Text) + "%",
+		"iso2Code":        query.ISO2Code,
+		"town":            query.Town,
+		"headquarterOnly": query.HeadquarterOnly,
+		"limit":           query.Limit,
+	}).Scan(&ranks).Error; err != nil {
+		s.db.Logger.Error(context.Background(), "Error during searching banks: "+err.Error())
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(ranks))
+	for _, rank := range ranks {
+		ids = append(ids, rank.ID)
+	}
+	var banks []models.Bank
+	if len(ids) > 0 {
+		if err := s.db.
+			Preload("Address.Town").
+			Preload("CodeType").
+			Preload("Country").
+			Preload("Name").
+			Preload("TimeZone").
+			Where("id IN ?", ids).
+			Find(&banks).Error; err != nil {
+			s.db.Logger.Error(context.Background(), "Error during searching banks: "+err.Error())
+			return nil, err
+		}
+	}
+
+	// banks are retrieved in any order, results keep the order of the ranks
+	positions := make(map[uint]int, len(ranks))
+	for i, rank := range ranks {
+		positions[rank.ID] = i
+	}
+	sort.Slice(banks, func(i, j int) bool { return positions[banks[i].ID] < positions[banks[j].ID] })
+
+	results := make([]models.SearchResult, 0, len(banks))
+	for _, bank := range banks {
+		results = append(results, models.NewSearchResult(bank, ranks[positions[bank.ID]].Rank, query.Text))
+	}
+	return results, nil
+}
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// AuditAction is the kind of mutating API request recorded in the audit log.
+type AuditAction string
+
+const (
+	AuditCreate  AuditAction = "create"
+	AuditReplace AuditAction = "replace"
+	AuditUpdate  AuditAction = "update"
+	AuditDelete  AuditAction = "delete"
+	AuditRestore AuditAction = "restore"
+	AuditImport  AuditAction = "import"
+)
+
+const (
+	// DefaultAuditLimit is the number of audit entries if no limit is requested.
+	DefaultAuditLimit = 100
+	// MaxAuditLimit is the largest number of audit entries of a query.
+	MaxAuditLimit = 1000
+)
+
+// AuditPayload is the JSON representation of a bank stored in the audit log, empty if there is no bank.
+type AuditPayload json.RawMessage
+
+// AuditEntry describes a mutating API request, who made it and the bank data before and after it.
+// Entries are never changed once recorded.
+type AuditEntry struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Actor is the authenticated client, or anonymous for requests without authentication
+	Actor     string      `gorm:"not null" json:"actor"`
+	RemoteIP  string      `gorm:"not null" json:"remoteIp"`
+	RequestID string      `gorm:"not null" json:"requestId"`
+	Action    AuditAction `gorm:"not null" json:"action"`
+	// SWIFTCode is the SWIFT code of the changed bank, empty for imports
+	SWIFTCode string `gorm:"not null" json:"swiftCode,omitempty"`
+	// Status is the status of the response, entries are recorded for failed requests too
+	Status int          `gorm:"not null" json:"status"`
+	Before AuditPayload `gorm:"type:jsonb" json:"before"`
+	After  AuditPayload `gorm:"type:jsonb" json:"after"`
+}
+
+// AuditQuery filters the audit log, empty fields and zero times mean no filter.
+type AuditQuery struct {
+	SWIFTCode string
+	Actor     string
+	// From is the earliest time of the entries, inclusive
+	From time.Time
+	// To is the latest time of the entries, exclusive
+	To    time.Time
+	Limit int
+}
+
+// NewAuditPayload returns the JSON representation of the bank in the full view, without its branches.
+func NewAuditPayload(bank Bank) (AuditPayload, error) {
+	bank.Branches = nil
+	bank.View = BankViewFull
+	data, err := json.Marshal(&bank)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// MarshalJSON returns the stored JSON, or null if there is no bank.
+func (p AuditPayload) MarshalJSON() ([]byte, error) {
+	if len(p) == 0 {
+		return []byte("null"), nil
+	}
+	return p, nil
+}
+
+// Value stores the payload as JSON text, or NULL if there is no bank.
+func (p AuditPayload) Value() (driver.Value, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+	return string(p), nil
+}
+
+// Scan reads the payload stored as JSON text.
+func (p *AuditPayload) Scan(value interface{}) error {
+	switch data := value.(type) {
+	case nil:
+		*p = nil
+	case []byte:
+		*p = append(AuditPayload(nil), data...)
+	case string:
+		*p = AuditPayload(data)
+	default:
+		return fmt.Errorf("cannot scan %T into audit payload", value)
+	}
+	return nil
+}
+
+// ParseAuditQuery converts the swiftCode, actor, from, to and limit query parameters to an AuditQuery.
+// Times are RFC 3339 times, an empty limit means the default limit.
+// It returns ErrRequestInvalid if any parameter is invalid.
+func ParseAuditQuery(swiftCode string, actor string, from string, to string, limit string) (AuditQuery, error) {
+	var details []string
+	query := AuditQuery{Actor: actor, Limit: DefaultAuditLimit}
+
+	if swiftCode != "" {
+		if err := ValidateSWIFTCode(swiftCode); err != nil {
+			details = append(details, "swiftCode must be a valid SWIFT code")
+		} else {
+			query.SWIFTCode = NormalizeSWIFTCode(swiftCode)
+		}
+	}
+	for _, param := range []struct {
+		name  string
+		value string
+		time  *time.Time
+	}{{"from", from, &query.From}, {"to", to, &query.To}} {
+		if param.value == "" {
+			continue
+		}
+		value, err := time.Parse(time.RFC3339, param.value)
+		if err != nil {
+			details = append(details, fmt.Sprintf("%s must be an RFC 3339 time like 2024-01-02T15:04:05Z", param.name))
+		}
+		*param.time = value
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		details = append(details, "from must be before to")
+	}
+	if limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > MaxAuditLimit {
+			details = append(details, fmt.Sprintf("limit must be a number between 1 and %d", MaxAuditLimit))
+		}
+		query.Limit = value
+	}
+
+	if len(details) > 0 {
+		return AuditQuery{}, &ErrRequestInvalid{Message: "Request invalid", Details: details}
+	}
+	return query, nil
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	_ "github.com/joho/godotenv/autoload"
+)
+
+// defaultSWIFTCountryExceptions lists territories whose banks use SWIFT codes of another country.
+const defaultSWIFTCountryExceptions = "GG:GB,JE:GB,IM:GB"
+
+// iso3166Data holds the ISO 3166-1 alpha-2 codes together with the canonical country names used by the application
+// and their semicolon separated aliases.
+//
+//go:embed data/iso3166.csv
+var iso3166Data []byte
+
+var (
+	countryNames, countryCodesByName = loadCountries()
+	swiftCountryExceptions           = loadSWIFTCountryExceptions(os.Getenv("SWIFT_COUNTRY_EXCEPTIONS"))
+)
+
+// loadCountries parses the embedded ISO 3166-1 dataset into a map of ISO2 code -> canonical country name
+// and a map of canonical country name or alias -> ISO2 code.
+func loadCountries() (map[string]string, map[string]string) {
+	records, err := csv.NewReader(bytes.NewReader(iso3166Data)).ReadAll()
+	if err != nil {
+		log.Fatalf("failed to parse embedded ISO 3166-1 data: %v", err)
+	}
+
+	names := make(map[string]string, len(records))
+	codes := make(map[string]string, len(records))
+	for _, record := range records[1:] {
+		names[record[0]] = record[1]
+		codes[record[1]] = record[0]
+		if record[2] == "" {
+			continue
+		}
+		for _, alias := range strings.Split(record[2], ";") {
+			codes[alias] = record[0]
+		}
+	}
+	return names, codes
+}
+
+// isISO3166Alpha2 reports whether the code is an assigned ISO 3166-1 alpha-2 country code.
+func isISO3166Alpha2(code string) bool {
+	_, ok := countryNames[code]
+	return ok
+}
+
+// isISO3166CountryName reports whether the name is a canonical country name or one of its aliases.
+func isISO3166CountryName(name string) bool {
+	_, ok := countryCodesByName[name]
+	return ok
+}
+
+// CanonicalCountryName validates the ISO2 code and the country name against the ISO 3166-1 registry
+// and returns the canonical name of the country.
+func CanonicalCountryName(ISO2Code string, CountryName string) (string, error) {
+	var details []string
+
+	for _, check := range []func() error{
+		func() error { return ValidateISO2Code(ISO2Code) },
+		func() error { return ValidateCountryName(CountryName) },
+		func() error { return ValidateCountry(ISO2Code, CountryName) },
+	} {
+		if err := check(); err != nil {
+			var errInvalidData *ErrInvalidData
+			if !errors.As(err, &errInvalidData) {
+				return "", err
+			}
+			details = append(details, errInvalidData.Details...)
+		}
+	}
+
+	if err := checkForValidationError(details, "Invalid country"); err != nil {
+		return "", err
+	}
+	return countryNames[ISO2Code], nil
+}
+
+// ListCountries returns all countries of the ISO 3166-1 registry sorted by ISO2 code, without bank counts.
+func ListCountries() []CountrySummary {
+	countries := make([]CountrySummary, 0, len(countryNames))
+	for code, name := range countryNames {
+		countries = append(countries, CountrySummary{ISO2Code: code, Country: name})
+	}
+	sort.Slice(countries, func(i, j int) bool {
+		return countries[i].ISO2Code < countries[j].ISO2Code
+	})
+	return countries
+}
+
+// loadSWIFTCountryExceptions parses a comma separated list of ISO2:SWIFT country code pairs,
+// e.g. "GG:GB,JE:GB", into a map of ISO2 code -> allowed SWIFT country codes.
+// If the list is empty, defaultSWIFTCountryExceptions is used.
+func loadSWIFTCountryExceptions(config string) map[string][]string {
+	if config == "" {
+		config = defaultSWIFTCountryExceptions
+	}
+
+	exceptions := make(map[string][]string)
+	for _, pair := range strings.Split(config, ",") {
+		iso2Code, swiftCountryCode, found := strings.Cut(strings.TrimSpace(pair), ":")
+		if !found || iso2Code == "" || swiftCountryCode == "" {
+			log.Printf("skipping malformed SWIFT country exception: %q", pair)
+			continue
+		}
+		exceptions[iso2Code] = append(exceptions[iso2Code], swiftCountryCode)
+	}
+	return exceptions
+}
+
+// isSWIFTCountryAllowed reports whether a bank from the ISO2 code country may use the SWIFT country code.
+func isSWIFTCountryAllowed(swiftCountryCode string, iso2Code string) bool {
+	if swiftCountryCode == iso2Code {
+		return true
+	}
+	for _, allowed := range swiftCountryExceptions[iso2Code] {
+		if allowed == swiftCountryCode {
+			return true
+		}
+	}
+	return false
+}
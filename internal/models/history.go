@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// ChangeOperation is the kind of change of a bank recorded in its history.
+type ChangeOperation string
+
+const (
+	ChangeCreated  ChangeOperation = "created"
+	ChangeUpdated  ChangeOperation = "updated"
+	ChangeDeleted  ChangeOperation = "deleted"
+	ChangeRestored ChangeOperation = "restored"
+)
+
+// asOfDateLayout is the layout of asOf parameters selecting the end of a day instead of a point in time.
+const asOfDateLayout = "2006-01-02"
+
+// BankChange is the data of a bank right after a change, or right before its deletion. Lookup table records
+// are copied, so the change is kept unchanged when they are updated or the bank is purged.
+// A change of the SWIFT code is recorded as the deletion of the old code and the creation of the new one.
+type BankChange struct {
+	ID          uint            `gorm:"primaryKey" json:"-"`
+	BankID      uint            `gorm:"not null" json:"-"`
+	SWIFTCode   string          `gorm:"not null" json:"swiftCode"`
+	Operation   ChangeOperation `gorm:"not null" json:"operation"`
+	BankName    string          `gorm:"not null" json:"bankName"`
+	Address     string          `gorm:"not null" json:"address"`
+	TownName    string          `gorm:"not null" json:"townName"`
+	ISO2Code    string          `gorm:"not null" json:"countryISO2"`
+	CountryName string          `gorm:"not null" json:"countryName"`
+	CodeType    string          `gorm:"not null" json:"codeType"`
+	TimeZone    string          `gorm:"not null" json:"timeZone"`
+	ChangedAt   time.Time       `gorm:"not null" json:"changedAt"`
+}
+
+// BankHistory lists the changes of the bank data stored under a SWIFT code, oldest first.
+type BankHistory struct {
+	SWIFTCode string       `json:"swiftCode"`
+	Changes   []BankChange `json:"changes"`
+}
+
+// ToBank returns the bank data recorded by the change, with the lookup table records set but without IDs.
+func (c BankChange) ToBank() Bank {
+	return Bank{
+		SWIFTCode: c.SWIFTCode,
+		CodeType:  CodeType{CodeType: c.CodeType},
+		Name:      BankName{Name: c.BankName},
+		Address:   BankAddress{Address: c.Address, Town: BankTown{Town: c.TownName}},
+		Country:   BankCountry{ISO2Code: c.ISO2Code, CountryName: c.CountryName},
+		TimeZone:  TimeZone{TimeZone: c.TimeZone},
+	}
+}
+
+// ParseAsOf converts the asOf query parameter, an RFC 3339 time or a date meaning the end of the day in UTC,
+// to the point in time of a lookup. An empty parameter means the current data, returned as the zero time.
+// It returns ErrRequestInvalid if the parameter is neither a time nor a date.
+func ParseAsOf(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if asOf, err := time.Parse(time.RFC3339, value); err == nil {
+		return asOf, nil
+	}
+	if date, err := time.Parse(asOfDateLayout, value); err == nil {
+		// the database stores microseconds, so the last microsecond of the day is still the same day
+		return date.AddDate(0, 0, 1).Add(-time.Microsecond), nil
+	}
+	return time.Time{}, &ErrRequestInvalid{
+		Message: "Request invalid",
+		Details: []string{"asOf must be an RFC 3339 time like 2024-01-02T15:04:05Z or a date like 2024-01-02"},
+	}
+}
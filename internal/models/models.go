@@ -1,5 +1,11 @@
 package models
 
+import (
+	"encoding/json"
+	"gorm.io/gorm"
+	"time"
+)
+
 type TimeZone struct {
 	ID       uint   `gorm:"primaryKey"`
 	TimeZone string `gorm:"unique;not null"`
@@ -7,8 +13,8 @@ type TimeZone struct {
 
 type BankCountry struct {
 	ID          uint   `gorm:"primaryKey"`
-	ISO2Code    string `gorm:"index:idx_country_iso2_code,unique;not null"`
-	CountryName string `gorm:"index:idx_country_iso2_code,unique;not null"`
+	ISO2Code    string `gorm:"unique;not null"`
+	CountryName string `gorm:"not null"`
 }
 
 type BankName struct {
@@ -34,8 +40,9 @@ type BankAddress struct {
 }
 
 type Bank struct {
-	ID            uint   `gorm:"primaryKey"`
-	SWIFTCode     string `gorm:"unique;not null"`
+	ID uint `gorm:"primaryKey"`
+	// SWIFTCode is unique among banks which are not deleted
+	SWIFTCode     string `gorm:"uniqueIndex:idx_banks_swift_code_active,where:deleted_at IS NULL;not null"`
 	CodeTypeID    uint
 	CodeType      CodeType `gorm:"foreignKey:CodeTypeID"`
 	NameID        uint
@@ -44,11 +51,17 @@ type Bank struct {
 	Address       BankAddress `gorm:"foreignKey:AddressID"`
 	CountryID     uint
 	Country       BankCountry `gorm:"foreignKey:CountryID"`
-	TimeZoneID    uint
+	TimeZoneID    *uint
 	TimeZone      TimeZone `gorm:"foreignKey:TimeZoneID"`
 	HeadquarterID *uint
 	Headquarter   *Bank  `gorm:"foreignKey:HeadquarterID"`
 	Branches      []Bank `gorm:"-"`
+	// DeletedAt is set for deleted banks, which are kept until they are purged
+	DeletedAt gorm.DeletedAt
+	// OriginalSWIFTCode is the SWIFT code as provided by the client, set only when it differs from the stored one
+	OriginalSWIFTCode string `gorm:"-"`
+	// View selects the fields of the JSON representation, set by the client
+	View BankView `gorm:"-"`
 }
 
 type BankDataCSV struct {
@@ -66,23 +79,161 @@ type CountrySWIFTCode struct {
 	ISO2Code string `json:"iso2Code"`
 	Country  string `json:"country"`
 	Banks    []Bank `json:"swiftCodes"`
+	// NextCursor is the cursor of the next page, empty on the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+	// Next is the link to the next page, empty on the last page
+	Next string `json:"next,omitempty"`
+}
+
+// Institution lists the banks sharing the first 8 characters of their SWIFT codes.
+type Institution struct {
+	InstitutionCode string `json:"institutionCode"`
+	// Headquarter is nil if only branches of the institution are stored
+	Headquarter *Bank `json:"headquarter"`
+	// Branches include branches whose headquarter is not stored
+	Branches []Bank `json:"branches"`
+}
+
+type CountrySummary struct {
+	ISO2Code  string `json:"iso2Code"`
+	Country   string `json:"country"`
+	BankCount int64  `json:"bankCount"`
 }
 
+// CreateBankRequest is the bank data of a request or an imported row. In requests, a missing code type is derived
+// from the SWIFT code and a missing time zone from the country if it has a single time zone, the town is optional.
 type CreateBankRequest struct {
 	Address       string `json:"address" csv:"ADDRESS"`
 	BankName      string `json:"bankName" csv:"NAME"`
 	ISO2Code      string `json:"countryISO2" csv:"COUNTRY ISO2 CODE"`
 	CountryName   string `json:"countryName" csv:"COUNTRY NAME"`
 	SWIFTCode     string `json:"swiftCode" csv:"SWIFT CODE"`
-	CodeType      string `json:"-" csv:"CODE TYPE"`
-	TownName      string `json:"-" csv:"TOWN NAME"`
-	TimeZone      string `json:"-" csv:"TIME ZONE"`
+	CodeType      string `json:"codeType" csv:"CODE TYPE"`
+	TownName      string `json:"townName" csv:"TOWN NAME"`
+	TimeZone      string `json:"timeZone" csv:"TIME ZONE"`
 	IsHeadquarter bool   `json:"isHeadquarter" csv:"-"`
+	// OriginalSWIFTCode is the SWIFT code as provided before normalization, set only when it was normalized
+	OriginalSWIFTCode string `json:"-" csv:"-"`
 }
 
-type Response struct {
-	Success bool     `json:"success"`
-	Status  int      `json:"status"`
-	Message string   `json:"message"`
+// UpdateBankRequest describes a partial update of a bank, fields left nil keep their current values.
+type UpdateBankRequest struct {
+	Address       *string `json:"address"`
+	BankName      *string `json:"bankName"`
+	ISO2Code      *string `json:"countryISO2"`
+	CountryName   *string `json:"countryName"`
+	SWIFTCode     *string `json:"swiftCode"`
+	CodeType      *string `json:"codeType"`
+	TownName      *string `json:"townName"`
+	TimeZone      *string `json:"timeZone"`
+	IsHeadquarter *bool   `json:"isHeadquarter"`
+}
+
+// UpsertResult describes how the bank data was stored by an upsert.
+type UpsertResult int
+
+const (
+	UpsertInserted UpsertResult = iota + 1
+	UpsertUpdated
+	UpsertUnchanged
+)
+
+// ImportSummary counts the rows of an import by their outcome.
+type ImportSummary struct {
+	Inserted  int `json:"inserted"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Failed    int `json:"failed"`
+}
+
+// RejectedRow describes a row of an import which could not be stored.
+type RejectedRow struct {
+	Line    int      `json:"line"`
+	Record  []string `json:"record"`
+	Error   string   `json:"error"`
 	Details []string `json:"details,omitempty"`
 }
+
+// ImportReport is the summary of an import together with the rows which could not be stored.
+type ImportReport struct {
+	ImportSummary
+	Rejected []RejectedRow `json:"rejected"`
+	// RejectsFile is the path of the file the rejected rows were written to, if any
+	RejectsFile string `json:"rejectsFile,omitempty"`
+	// DryRun is set if the report describes what an import would do, without storing any bank data
+	DryRun bool `json:"dryRun,omitempty"`
+	// OrphanBranches lists the SWIFT codes of branches whose headquarter is neither imported nor stored,
+	// reported by dry runs only
+	OrphanBranches []string `json:"orphanBranches,omitempty"`
+}
+
+// ImportStatus is the state of an import running in the background.
+type ImportStatus string
+
+const (
+	ImportRunning   ImportStatus = "running"
+	ImportCompleted ImportStatus = "completed"
+	ImportFailed    ImportStatus = "failed"
+)
+
+// ImportJob describes an import of an uploaded file running in the background.
+type ImportJob struct {
+	ID       string       `json:"id"`
+	FileName string       `json:"fileName"`
+	Status   ImportStatus `json:"status"`
+	// TotalRows is the number of data rows of the uploaded file
+	TotalRows int `json:"totalRows"`
+	// Progress counts the rows processed so far by their outcome
+	Progress ImportSummary `json:"progress"`
+	// Report is set when the import finishes, also if it failed after processing some rows
+	Report     *ImportReport `json:"report,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	FinishedAt *time.Time    `json:"finishedAt,omitempty"`
+}
+
+// LookupRequest lists the SWIFT codes of a bulk lookup.
+type LookupRequest struct {
+	SWIFTCodes []string `json:"swiftCodes"`
+}
+
+// LookupResult is either the bank with the requested SWIFT code or the error explaining why it was not found.
+type LookupResult struct {
+	Bank  *Bank     `json:"bank,omitempty"`
+	Error *Response `json:"error,omitempty"`
+}
+
+// LookupResponse maps every requested SWIFT code to its result, together with the number of results by outcome.
+type LookupResponse struct {
+	Found    int                     `json:"found"`
+	NotFound int                     `json:"notFound"`
+	Invalid  int                     `json:"invalid"`
+	Results  map[string]LookupResult `json:"results"`
+}
+
+// BatchRequest adds multiple banks at once. In atomic mode all banks are added or none of them.
+type BatchRequest struct {
+	Atomic bool `json:"atomic"`
+	// Banks are decoded one by one, so an invalid bank does not reject the whole request
+	Banks []json.RawMessage `json:"banks"`
+}
+
+// BatchResponse reports the outcome of every bank of a batch in the order of the request.
+type BatchResponse struct {
+	// Status is the status of the whole batch: 207 in best-effort mode, 201 or the status of the first failed bank
+	// in atomic mode
+	Status  int        `json:"status"`
+	Atomic  bool       `json:"atomic"`
+	Created int        `json:"created"`
+	Failed  int        `json:"failed"`
+	Results []Response `json:"results"`
+}
+
+type Response struct {
+	Success           bool     `json:"success"`
+	Status            int      `json:"status"`
+	Message           string   `json:"message"`
+	SWIFTCode         string   `json:"swiftCode,omitempty"`
+	OriginalSWIFTCode string   `json:"originalSwiftCode,omitempty"`
+	Details           []string `json:"details,omitempty"`
+}
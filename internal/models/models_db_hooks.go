@@ -11,6 +11,7 @@ type usageCheckInterface interface {
 	Bank | BankAddress
 }
 
+// checkUsageOfElement reports ErrInUse if the record is used by any of the elements.
 func checkUsageOfElement[T usageCheckInterface](tx *gorm.DB, elem []T, tableName string, id uint) error {
 	tx.Logger.Info(context.Background(), fmt.Sprintf("Validating usage of record from %s before deleting", tableName))
 
@@ -22,11 +23,23 @@ func checkUsageOfElement[T usageCheckInterface](tx *gorm.DB, elem []T, tableName
 	return nil
 }
 
+func (tz *TimeZone) BeforeCreate(tx *gorm.DB) (err error) {
+	tx.Logger.Info(context.Background(), "Validating time zone data before creating")
+
+	if err := ValidateTimeZone(tz.TimeZone); err != nil {
+		tx.Logger.Error(context.Background(), err.Error())
+		return err
+	}
+	return nil
+}
+
+// BeforeDelete rejects deleting the time zone while a bank uses it. Deleted banks are kept until they are purged,
+// so they are looked up too, like by the other hooks of records used by banks.
 func (tz *TimeZone) BeforeDelete(tx *gorm.DB) (err error) {
 	tx.Logger.Info(context.Background(), "Validating timezone before deleting")
 
 	var banks []Bank
-	if err := tx.Where("time_zone_id = ?", tz.ID).Find(&banks).Error; err != nil {
+	if err := tx.Unscoped().Where("time_zone_id = ?", tz.ID).Find(&banks).Error; err != nil {
 		tx.Logger.Error(context.Background(), "Error while fetching banks: "+err.Error())
 		return err
 	}
@@ -36,25 +49,23 @@ func (tz *TimeZone) BeforeDelete(tx *gorm.DB) (err error) {
 func (bc *BankCountry) BeforeCreate(tx *gorm.DB) (err error) {
 	tx.Logger.Info(context.Background(), "Validating bank country data before creating")
 
-	if err := ValidateISO2Code(bc.ISO2Code); err != nil {
-		tx.Logger.Error(context.Background(), err.Error())
-		return err
-	}
-	if err := ValidateCountryName(bc.CountryName); err != nil {
+	countryName, err := CanonicalCountryName(bc.ISO2Code, bc.CountryName)
+	if err != nil {
 		tx.Logger.Error(context.Background(), err.Error())
 		return err
 	}
 	// made strict rules lowercase will not get here
 	//bc.ISO2Code = strings.ToUpper(bc.ISO2Code)
-	//bc.CountryName = strings.ToUpper(bc.CountryName)
+	bc.CountryName = countryName
 	return nil
 }
 
+// BeforeDelete rejects deleting the country while a bank, also a deleted one, uses it.
 func (bc *BankCountry) BeforeDelete(tx *gorm.DB) (err error) {
 	tx.Logger.Info(context.Background(), "Validating bank country before deleting")
 
 	var banks []Bank
-	if err := tx.Where("country_id = ?", bc.ID).Find(&banks).Error; err != nil {
+	if err := tx.Unscoped().Where("country_id = ?", bc.ID).Find(&banks).Error; err != nil {
 		tx.Logger.Error(context.Background(), "Error while fetching banks: "+err.Error())
 		return nil
 
@@ -72,11 +83,12 @@ func (bn *BankName) BeforeCreate(tx *gorm.DB) (err error) {
 	return nil
 }
 
+// BeforeDelete rejects deleting the bank name while a bank, also a deleted one, uses it.
 func (bn *BankName) BeforeDelete(tx *gorm.DB) (err error) {
 	tx.Logger.Info(context.Background(), "Validating bank name before deleting")
 
 	var banks []Bank
-	if err := tx.Where("name_id = ?", bn.ID).Find(&banks).Error; err != nil {
+	if err := tx.Unscoped().Where("name_id = ?", bn.ID).Find(&banks).Error; err != nil {
 		tx.Logger.Error(context.Background(), "Error while fetching banks: "+err.Error())
 		return err
 
@@ -84,11 +96,12 @@ func (bn *BankName) BeforeDelete(tx *gorm.DB) (err error) {
 	return checkUsageOfElement(tx, banks, tx.Statement.Table, bn.ID)
 }
 
+// BeforeDelete rejects deleting the code type while a bank, also a deleted one, uses it.
 func (ct *CodeType) BeforeDelete(tx *gorm.DB) (err error) {
 	tx.Logger.Info(context.Background(), "Validating code type before deleting")
 
 	var bank []Bank
-	if err := tx.Where("code_type_id = ?", ct.ID).Find(&bank).Error; err != nil {
+	if err := tx.Unscoped().Where("code_type_id = ?", ct.ID).Find(&bank).Error; err != nil {
 		tx.Logger.Error(context.Background(), "Error while fetching banks: "+err.Error())
 		return err
 	}
@@ -106,6 +119,7 @@ func (bt *BankTown) BeforeDelete(tx *gorm.DB) (err error) {
 	return checkUsageOfElement(tx, addresses, tx.Statement.Table, bt.ID)
 }
 
+// BeforeDelete rejects deleting the address while a bank, also a deleted one, uses it.
 func (ba *BankAddress) BeforeDelete(tx *gorm.DB) (err error) {
 	tx.Logger.Info(context.Background(), "Validating bank address before deleting")
 
@@ -122,7 +136,7 @@ func (ba *BankAddress) BeforeDelete(tx *gorm.DB) (err error) {
 		}
 
 		var banks []Bank
-		if err := tx.Where("address_id = ?", ba.ID).Find(&banks).Error; err != nil {
+		if err := tx.Unscoped().Where("address_id = ?", ba.ID).Find(&banks).Error; err != nil {
 			tx.Logger.Error(context.Background(), "Error while fetching banks: "+err.Error())
 			return err
 		}
@@ -139,17 +153,30 @@ func (b *Bank) BeforeCreate(tx *gorm.DB) (err error) {
 	}
 	// made strict rules lowercase will not get here
 	//b.SWIFTCode = strings.ToUpper(b.SWIFTCode)
+	b.SWIFTCode = NormalizeSWIFTCode(b.SWIFTCode)
+
+	country := b.Country
+	if country.ISO2Code == "" {
+		if err := tx.Where("id = ?", b.CountryID).First(&country).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// missing country is reported by the foreign key constraint
+				return nil
+			}
+			tx.Logger.Error(context.Background(), "Error while fetching bank country: "+err.Error())
+			return err
+		}
+	}
+	if err := ValidateSWIFTCountry(b.SWIFTCode, country.ISO2Code); err != nil {
+		tx.Logger.Error(context.Background(), err.Error())
+		return err
+	}
 	return nil
 }
 
 func (b *Bank) AfterCreate(tx *gorm.DB) (err error) {
 	tx.Logger.Info(context.Background(), "Bank data created successfully, linking to headquarter")
 
-	mainCode := b.SWIFTCode[:8]
-	if b.IsHeadquarterBank() {
-		return b.linkBranches(tx, mainCode)
-	}
-	return b.linkToHeadquarter(tx, mainCode)
+	return b.LinkHeadquarter(tx)
 }
 
 func (b *Bank) BeforeDelete(tx *gorm.DB) (err error) {
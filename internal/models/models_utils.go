@@ -4,16 +4,27 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"gorm.io/gorm"
+	"net/http"
 	"strings"
+	"time"
 )
 
+// SameInstitution is a query scope selecting the banks whose SWIFT code starts with the institution code,
+// the first 8 characters shared by a headquarter and its branches. The headquarter is selected too.
+func SameInstitution(institutionCode string) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("swift_code LIKE ?", institutionCode+"%")
+	}
+}
+
 func (b *Bank) linkBranches(tx *gorm.DB, mainCode string) error {
 	tx.Logger.Info(context.Background(), "Headquarter bank, linking branches")
 
 	return tx.Transaction(func(tx *gorm.DB) error {
 		var branches []Bank
-		if err := tx.Where("swift_code LIKE ?", mainCode+"%").Find(&branches).Error; err != nil {
+		if err := tx.Scopes(SameInstitution(mainCode)).Find(&branches).Error; err != nil {
 			if !errors.Is(err, gorm.ErrRecordNotFound) {
 				tx.Logger.Error(context.Background(), "Error while fetching branches: "+err.Error())
 				return err
@@ -49,20 +60,77 @@ func (b *Bank) linkToHeadquarter(tx *gorm.DB, mainCode string) error {
 	})
 }
 
+// LinkHeadquarter links a headquarter bank with its branches or a branch bank with its headquarter,
+// based on the first 8 characters of the SWIFT code.
+func (b *Bank) LinkHeadquarter(tx *gorm.DB) error {
+	mainCode := b.SWIFTCode[:8]
+	if b.IsHeadquarterBank() {
+		return b.linkBranches(tx, mainCode)
+	}
+	return b.linkToHeadquarter(tx, mainCode)
+}
+
+// ToCreateBankRequest converts the bank with loaded associations to the request which would create it.
+func (b *Bank) ToCreateBankRequest() CreateBankRequest {
+	return CreateBankRequest{
+		Address:       b.Address.Address,
+		BankName:      b.Name.Name,
+		ISO2Code:      b.Country.ISO2Code,
+		CountryName:   b.Country.CountryName,
+		SWIFTCode:     b.SWIFTCode,
+		CodeType:      b.CodeType.CodeType,
+		TownName:      b.Address.Town.Town,
+		TimeZone:      b.TimeZone.TimeZone,
+		IsHeadquarter: b.IsHeadquarterBank(),
+	}
+}
+
 func (b *Bank) IsHeadquarterBank() bool {
 	return strings.HasSuffix(b.SWIFTCode, "XXX")
 }
 
+// NormalizeSWIFTCode converts an 8 characters long SWIFT code (BIC8) to its 11 characters long headquarter form (BIC11).
+// Codes of any other length are returned unchanged.
+func NormalizeSWIFTCode(SWIFTCode string) string {
+	if len(SWIFTCode) == 8 {
+		return SWIFTCode + "XXX"
+	}
+	return SWIFTCode
+}
+
+const (
+	codeTypeBIC8  = "BIC8"
+	codeTypeBIC11 = "BIC11"
+)
+
+// SWIFTCodeType returns the code type of an 8 (BIC8) or 11 (BIC11) characters long SWIFT code,
+// or an empty string for codes of any other length.
+func SWIFTCodeType(SWIFTCode string) string {
+	switch len(SWIFTCode) {
+	case 8:
+		return codeTypeBIC8
+	case 11:
+		return codeTypeBIC11
+	default:
+		return ""
+	}
+}
+
 func (b *Bank) MarshalJSON() ([]byte, error) {
 	type Alias Bank
 	aux := &struct {
-		Address       string  `json:"address"`
-		Name          string  `json:"bankName"`
-		ISO2          string  `json:"countryISO2"`
-		Country       string  `json:"countryName,omitempty"`
-		IsHeadquarter bool    `json:"isHeadquarter"`
-		SWIFTCode     string  `json:"swiftCode"`
-		Branches      *[]Bank `json:"branches,omitempty"`
+		Address       string     `json:"address"`
+		Name          string     `json:"bankName"`
+		ISO2          string     `json:"countryISO2"`
+		Country       string     `json:"countryName,omitempty"`
+		IsHeadquarter bool       `json:"isHeadquarter"`
+		SWIFTCode     string     `json:"swiftCode"`
+		OriginalCode  string     `json:"originalSwiftCode,omitempty"`
+		CodeType      *string    `json:"codeType,omitempty"`
+		TownName      *string    `json:"townName,omitempty"`
+		TimeZone      *string    `json:"timeZone,omitempty"`
+		DeletedAt     *time.Time `json:"deletedAt,omitempty"`
+		Branches      *[]Bank    `json:"branches,omitempty"`
 	}{
 		Address:       b.Address.Address,
 		Name:          b.Name.Name,
@@ -70,28 +138,176 @@ func (b *Bank) MarshalJSON() ([]byte, error) {
 		Country:       b.Country.CountryName,
 		IsHeadquarter: b.IsHeadquarterBank(),
 		SWIFTCode:     b.SWIFTCode,
+		OriginalCode:  b.OriginalSWIFTCode,
+	}
+	// the full view lists the fields even if they are empty
+	if b.View == BankViewFull {
+		aux.CodeType = &b.CodeType.CodeType
+		aux.TownName = &b.Address.Town.Town
+		aux.TimeZone = &b.TimeZone.TimeZone
+	}
+	if b.DeletedAt.Valid {
+		aux.DeletedAt = &b.DeletedAt.Time
 	}
 
 	if b.IsHeadquarterBank() && b.Branches != nil {
 		aux.Branches = &b.Branches
 		if len(b.Branches) == 0 {
 			aux.Branches = &[]Bank{}
+		} else if b.View != BankViewDefault {
+			branches := make([]Bank, len(b.Branches))
+			for i, branch := range b.Branches {
+				branch.View = b.View
+				branches[i] = branch
+			}
+			aux.Branches = &branches
 		}
 	}
 
 	return json.Marshal(aux)
 }
 
-func (c *CreateBankRequest) checkIfRequestIsCorrect() error {
-	var requestErrors []string
+// NewLookupResponse maps the requested SWIFT codes to the found banks, keyed by their normalized SWIFT codes.
+// Codes failing validation are reported as invalid, valid codes without a bank as not found.
+func NewLookupResponse(swiftCodes []string, banks map[string]Bank) LookupResponse {
+	response := LookupResponse{Results: make(map[string]LookupResult, len(swiftCodes))}
+	for _, swiftCode := range swiftCodes {
+		if _, ok := response.Results[swiftCode]; ok {
+			continue
+		}
+		if err := ValidateSWIFTCode(swiftCode); err != nil {
+			errResponse := MapErrorToStatusCode(err)
+			errResponse.SWIFTCode = swiftCode
+			response.Results[swiftCode] = LookupResult{Error: &errResponse}
+			response.Invalid++
+			continue
+		}
 
-	checks := []func() error{
-		func() error { return ValidateSWIFTCode(c.SWIFTCode) },
-		func() error { return ValidateISO2Code(c.ISO2Code) },
-		func() error { return ValidateCountryName(c.CountryName) },
-		func() error { return ValidateBankName(c.BankName) },
-		func() error { return ValidateHeadquarter(c.SWIFTCode, c.IsHeadquarter) },
+		normalizedSWIFTCode := NormalizeSWIFTCode(swiftCode)
+		bank, ok := banks[normalizedSWIFTCode]
+		if !ok {
+			errResponse := MapErrorToStatusCode(fmt.Errorf("bank %s: %w", normalizedSWIFTCode, gorm.ErrRecordNotFound))
+			errResponse.SWIFTCode = normalizedSWIFTCode
+			response.Results[swiftCode] = LookupResult{Error: &errResponse}
+			response.NotFound++
+			continue
+		}
+		if normalizedSWIFTCode != swiftCode {
+			bank.OriginalSWIFTCode = swiftCode
+		}
+		response.Results[swiftCode] = LookupResult{Bank: &bank}
+		response.Found++
+	}
+	return response
+}
+
+// NewBatchResponse reports the outcome of every request of a batch, using the status of its error if it failed.
+// In atomic mode, banks are not added if any request failed, the requests which did not fail get status 424.
+func NewBatchResponse(atomic bool, requests []CreateBankRequest, errs []error) BatchResponse {
+	response := BatchResponse{Status: http.StatusMultiStatus, Atomic: atomic, Results: make([]Response, len(requests))}
+	for i, err := range errs {
+		if err != nil {
+			response.Results[i] = MapErrorToStatusCode(err)
+			response.Failed++
+		} else {
+			response.Results[i] = Response{Success: true, Status: http.StatusCreated, Message: "Bank data added successfully"}
+			response.Created++
+		}
+		response.Results[i].SWIFTCode = requests[i].SWIFTCode
+		response.Results[i].OriginalSWIFTCode = requests[i].OriginalSWIFTCode
+	}
+	if !atomic {
+		return response
+	}
+
+	response.Status = http.StatusCreated
+	if response.Failed == 0 {
+		return response
+	}
+	for i, result := range response.Results {
+		if result.Success {
+			response.Results[i] = Response{
+				Status:            http.StatusFailedDependency,
+				Message:           "Bank data not added, another bank of the atomic batch failed",
+				SWIFTCode:         result.SWIFTCode,
+				OriginalSWIFTCode: result.OriginalSWIFTCode,
+			}
+		} else if response.Status == http.StatusCreated {
+			response.Status = result.Status
+		}
+	}
+	response.Created = 0
+	return response
+}
+
+// SameBankData reports whether both requests describe the same stored bank data.
+// Only the fields stored in the database are compared, the headquarter flag is derived from the SWIFT code.
+func (c *CreateBankRequest) SameBankData(other CreateBankRequest) bool {
+	return c.Address == other.Address &&
+		c.BankName == other.BankName &&
+		c.ISO2Code == other.ISO2Code &&
+		c.CountryName == other.CountryName &&
+		c.SWIFTCode == other.SWIFTCode &&
+		c.CodeType == other.CodeType &&
+		c.TownName == other.TownName &&
+		c.TimeZone == other.TimeZone
+}
+
+// Add counts the upsert result in the summary.
+func (s *ImportSummary) Add(result UpsertResult) {
+	switch result {
+	case UpsertInserted:
+		s.Inserted++
+	case UpsertUpdated:
+		s.Updated++
+	case UpsertUnchanged:
+		s.Unchanged++
+	}
+}
+
+// Reject counts the row as failed and records it in the report with the error and its validation details.
+func (r *ImportReport) Reject(line int, record []string, err error) {
+	r.Failed++
+
+	rejected := RejectedRow{Line: line, Record: append([]string(nil), record...), Error: err.Error()}
+	var errInvalidData *ErrInvalidData
+	var errRequestInvalid *ErrRequestInvalid
+	if errors.As(err, &errInvalidData) {
+		rejected.Error = errInvalidData.Message
+		rejected.Details = errInvalidData.Details
+	} else if errors.As(err, &errRequestInvalid) {
+		rejected.Details = errRequestInvalid.Details
+	}
+	r.Rejected = append(r.Rejected, rejected)
+}
+
+// Normalize converts a BIC8 SWIFT code of the request to its BIC11 form, keeping the original value.
+// The BIC8 code type of the code is converted too.
+func (c *CreateBankRequest) Normalize() {
+	if normalized := NormalizeSWIFTCode(c.SWIFTCode); normalized != c.SWIFTCode {
+		c.OriginalSWIFTCode = c.SWIFTCode
+		c.SWIFTCode = normalized
+		if c.CodeType == codeTypeBIC8 {
+			c.CodeType = codeTypeBIC11
+		}
+	}
+}
+
+// DeriveMissingFields sets the code type from the SWIFT code and the time zone from the country,
+// if they are not provided. The time zone stays empty if the country has more than one time zone.
+func (c *CreateBankRequest) DeriveMissingFields() {
+	if c.CodeType == "" {
+		c.CodeType = SWIFTCodeType(c.SWIFTCode)
+	}
+	if c.TimeZone == "" {
+		c.TimeZone = DefaultTimeZone(c.ISO2Code)
 	}
+}
+
+// runRequestChecks runs all checks, reporting the details of every failed check in ErrRequestInvalid.
+// Errors other than ErrInvalidData are returned immediately.
+func runRequestChecks(checks []func() error) error {
+	var requestErrors []string
 
 	for _, check := range checks {
 		if err := check(); err != nil {
@@ -111,6 +327,73 @@ func (c *CreateBankRequest) checkIfRequestIsCorrect() error {
 	return nil
 }
 
+// requestChecks returns the checks of the fields required in every request.
+func (c *CreateBankRequest) requestChecks() []func() error {
+	return []func() error{
+		func() error { return ValidateSWIFTCode(c.SWIFTCode) },
+		func() error { return ValidateISO2Code(c.ISO2Code) },
+		func() error { return ValidateCountryName(c.CountryName) },
+		func() error { return ValidateCountry(c.ISO2Code, c.CountryName) },
+		func() error { return ValidateBankName(c.BankName) },
+		func() error { return ValidateHeadquarter(c.SWIFTCode, c.IsHeadquarter) },
+		func() error { return ValidateSWIFTCountry(c.SWIFTCode, c.ISO2Code) },
+	}
+}
+
+// codeTypeCheck returns the check of the code type against the SWIFT code.
+func (c *CreateBankRequest) codeTypeCheck() func() error {
+	return func() error { return ValidateCodeType(c.CodeType, c.SWIFTCode) }
+}
+
+// townNameCheck returns the check of the optional town name.
+func (c *CreateBankRequest) townNameCheck() func() error {
+	return func() error { return ValidateTownName(c.TownName) }
+}
+
+// timeZoneChecks returns the checks of the time zone and its country.
+func (c *CreateBankRequest) timeZoneChecks() []func() error {
+	return []func() error{
+		func() error { return ValidateTimeZone(c.TimeZone) },
+		func() error { return ValidateTimeZoneCountry(c.TimeZone, c.ISO2Code) },
+	}
+}
+
+func (c *CreateBankRequest) checkIfRequestIsCorrect() error {
+	checks := c.requestChecks()
+	if c.CodeType != "" {
+		checks = append(checks, c.codeTypeCheck())
+	}
+	checks = append(checks, c.townNameCheck())
+	if c.TimeZone != "" {
+		checks = append(checks, c.timeZoneChecks()...)
+	}
+	return runRequestChecks(checks)
+}
+
+// Validate checks all fields of the request, reporting every problem in ErrRequestInvalid details.
+// The code type and the time zone are checked only if they are set.
+func (c *CreateBankRequest) Validate() error {
+	return c.checkIfRequestIsCorrect()
+}
+
+// ValidateForStorage checks the fields validated by the database hooks when the bank data is stored,
+// including the optional time zone, reporting every problem in ErrRequestInvalid details.
+// Unlike Validate, it does not require the headquarter flag, which is not part of imported data.
+func (c *CreateBankRequest) ValidateForStorage() error {
+	checks := []func() error{
+		func() error { return ValidateSWIFTCode(c.SWIFTCode) },
+		func() error { return ValidateISO2Code(c.ISO2Code) },
+		func() error { return ValidateCountryName(c.CountryName) },
+		func() error { return ValidateCountry(c.ISO2Code, c.CountryName) },
+		func() error { return ValidateBankName(c.BankName) },
+		func() error { return ValidateSWIFTCountry(c.SWIFTCode, c.ISO2Code) },
+	}
+	if c.TimeZone != "" {
+		checks = append(checks, c.timeZoneChecks()...)
+	}
+	return runRequestChecks(checks)
+}
+
 func (c *CreateBankRequest) UnmarshalJSON(data []byte) error {
 	type Alias CreateBankRequest
 	aux := &struct {
@@ -121,6 +404,87 @@ func (c *CreateBankRequest) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &aux); err != nil {
 		return err
 	}
+	c.Normalize()
+	c.DeriveMissingFields()
 
 	return c.checkIfRequestIsCorrect()
 }
+
+// NewUpdateBankRequest creates an update replacing all client provided fields with values of the request.
+func NewUpdateBankRequest(requestData CreateBankRequest) UpdateBankRequest {
+	return UpdateBankRequest{
+		Address:       &requestData.Address,
+		BankName:      &requestData.BankName,
+		ISO2Code:      &requestData.ISO2Code,
+		CountryName:   &requestData.CountryName,
+		SWIFTCode:     &requestData.SWIFTCode,
+		CodeType:      &requestData.CodeType,
+		TownName:      &requestData.TownName,
+		TimeZone:      &requestData.TimeZone,
+		IsHeadquarter: &requestData.IsHeadquarter,
+	}
+}
+
+// Validate checks the data of a bank updated by ApplyTo, reporting every problem in ErrRequestInvalid details.
+// The code type, town name and time zone are checked only if the update provides them,
+// so banks stored with other values, e.g. imported ones, can still be updated.
+func (u *UpdateBankRequest) Validate(updatedData CreateBankRequest) error {
+	checks := updatedData.requestChecks()
+	if u.CodeType != nil {
+		checks = append(checks, updatedData.codeTypeCheck())
+	}
+	if u.TownName != nil {
+		checks = append(checks, updatedData.townNameCheck())
+	}
+	if u.TimeZone != nil && *u.TimeZone != "" {
+		checks = append(checks, updatedData.timeZoneChecks()...)
+	}
+	return runRequestChecks(checks)
+}
+
+// ApplyTo returns the request with provided fields of the update applied.
+// If the headquarter status is not provided, it is derived from the resulting SWIFT code.
+// The code type of a changed SWIFT code and the time zone of a changed country are derived too, if not provided.
+func (u *UpdateBankRequest) ApplyTo(requestData CreateBankRequest) CreateBankRequest {
+	countryChanged := u.ISO2Code != nil && *u.ISO2Code != requestData.ISO2Code
+	if u.Address != nil {
+		requestData.Address = *u.Address
+	}
+	if u.BankName != nil {
+		requestData.BankName = *u.BankName
+	}
+	if u.ISO2Code != nil {
+		requestData.ISO2Code = *u.ISO2Code
+	}
+	if u.CountryName != nil {
+		requestData.CountryName = *u.CountryName
+	}
+	if u.SWIFTCode != nil {
+		requestData.SWIFTCode = *u.SWIFTCode
+		requestData.OriginalSWIFTCode = ""
+	}
+	if u.CodeType != nil {
+		requestData.CodeType = *u.CodeType
+	}
+	if u.TownName != nil {
+		requestData.TownName = *u.TownName
+	}
+	if u.TimeZone != nil {
+		requestData.TimeZone = *u.TimeZone
+	}
+	requestData.Normalize()
+
+	if u.CodeType == nil && u.SWIFTCode != nil {
+		requestData.CodeType = SWIFTCodeType(requestData.SWIFTCode)
+	}
+	if u.TimeZone == nil && countryChanged {
+		requestData.TimeZone = DefaultTimeZone(requestData.ISO2Code)
+	}
+
+	if u.IsHeadquarter != nil {
+		requestData.IsHeadquarter = *u.IsHeadquarter
+	} else {
+		requestData.IsHeadquarter = strings.HasSuffix(requestData.SWIFTCode, "XXX")
+	}
+	return requestData
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// BankSort is the order of the banks of a country listing.
+type BankSort string
+
+const (
+	SortBySWIFTCode BankSort = "swiftCode"
+	SortByBankName  BankSort = "bankName"
+	SortByTown      BankSort = "town"
+)
+
+// MaxPageLimit is the largest number of banks of a page.
+const MaxPageLimit = 1000
+
+// PageCursor points to the last bank of a page, the next page starts after it.
+// Banks are ordered by the sorted value and then by SWIFT code, which is unique.
+type PageCursor struct {
+	Sort      BankSort `json:"sort"`
+	Value     string   `json:"value"`
+	SWIFTCode string   `json:"swiftCode"`
+}
+
+// PageRequest selects a page of a country listing. A zero limit means all banks, as listings without a limit
+// returned every bank before pagination was introduced.
+type PageRequest struct {
+	Limit int
+	Sort  BankSort
+	// After is the cursor of the previous page, nil for the first page
+	After *PageCursor
+}
+
+// Encode returns the cursor as an opaque string which can be passed in a URL.
+func (c PageCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// SortValue returns the value of the bank with loaded associations the banks are sorted by.
+func (s BankSort) SortValue(bank Bank) string {
+	switch s {
+	case SortByBankName:
+		return bank.Name.Name
+	case SortByTown:
+		return bank.Address.Town.Town
+	default:
+		return bank.SWIFTCode
+	}
+}
+
+// ParsePageRequest converts the limit, cursor and sort query parameters to a PageRequest.
+// Empty parameters mean no limit, the first page and the order by SWIFT code.
+// It returns ErrRequestInvalid if any parameter is invalid or the cursor belongs to another order.
+func ParsePageRequest(limit string, cursor string, sort string) (PageRequest, error) {
+	var details []string
+	page := PageRequest{Sort: BankSort(sort)}
+
+	if limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > MaxPageLimit {
+			details = append(details, fmt.Sprintf("limit must be a number between 1 and %d", MaxPageLimit))
+		}
+		page.Limit = value
+	}
+
+	switch page.Sort {
+	case "":
+		page.Sort = SortBySWIFTCode
+	case SortBySWIFTCode, SortByBankName, SortByTown:
+	default:
+		details = append(details, fmt.Sprintf("sort must be one of %s, %s or %s", SortBySWIFTCode, SortByBankName, SortByTown))
+	}
+
+	if cursor != "" {
+		var after PageCursor
+		data, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err == nil {
+			err = json.Unmarshal(data, &after)
+		}
+		switch {
+		case err != nil || after.SWIFTCode == "":
+			details = append(details, "cursor is malformed")
+		case after.Sort != page.Sort:
+			details = append(details, "cursor belongs to a listing with another sort order")
+		default:
+			page.After = &after
+		}
+	}
+
+	if len(details) > 0 {
+		return PageRequest{}, &ErrRequestInvalid{Message: "Request invalid", Details: details}
+	}
+	return page, nil
+}
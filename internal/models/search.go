@@ -0,0 +1,128 @@
+package models
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultSearchLimit is the number of search results if no limit is requested.
+	DefaultSearchLimit = 20
+	// MaxSearchLimit is the largest number of search results.
+	MaxSearchLimit = 100
+	// minSearchTextLength is the shortest searched text, shorter texts match too many banks.
+	minSearchTextLength = 2
+	// maxSearchTextLength is the longest searched text.
+	maxSearchTextLength = 100
+)
+
+// SearchQuery describes a search of banks by name, address or town.
+type SearchQuery struct {
+	Text string
+	// ISO2Code limits the results to banks of the country, if set
+	ISO2Code string
+	// Town limits the results to banks of the town, ignoring case, if set
+	Town            string
+	HeadquarterOnly bool
+	Limit           int
+}
+
+// SearchResult is a bank matching a search together with its rank and the highlighted matches.
+type SearchResult struct {
+	Bank Bank    `json:"bank"`
+	Rank float64 `json:"rank"`
+	// Highlights are the values of the matching fields with the matched words wrapped in <em> tags, by field name
+	Highlights map[string]string `json:"highlights"`
+}
+
+// SearchResponse lists the results of a search ordered from the best match.
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+}
+
+// ParseSearchQuery converts the query parameters of a search to a SearchQuery.
+// Empty country, town, headquarterOnly and limit parameters mean no filter and the default limit.
+// It returns ErrRequestInvalid if any parameter is invalid.
+func ParseSearchQuery(text string, iso2Code string, town string, headquarterOnly string, limit string) (SearchQuery, error) {
+	var details []string
+	query := SearchQuery{Text: strings.TrimSpace(text), ISO2Code: iso2Code, Town: strings.TrimSpace(town), Limit: DefaultSearchLimit}
+
+	if length := utf8.RuneCountInString(query.Text); length < minSearchTextLength || length > maxSearchTextLength {
+		details = append(details, fmt.Sprintf("q must have between %d and %d characters", minSearchTextLength, maxSearchTextLength))
+	}
+	if iso2Code != "" {
+		if err := ValidateISO2Code(iso2Code); err != nil {
+			details = append(details, "country must be an ISO2 code")
+		}
+	}
+	if headquarterOnly != "" {
+		value, err := strconv.ParseBool(headquarterOnly)
+		if err != nil {
+			details = append(details, "headquarterOnly must be true or false")
+		}
+		query.HeadquarterOnly = value
+	}
+	if limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > MaxSearchLimit {
+			details = append(details, fmt.Sprintf("limit must be a number between 1 and %d", MaxSearchLimit))
+		}
+		query.Limit = value
+	}
+
+	if len(details) > 0 {
+		return SearchQuery{}, &ErrRequestInvalid{Message: "Request invalid", Details: details}
+	}
+	return query, nil
+}
+
+// searchTermsPattern returns a case-insensitive pattern matching any word of the text, preferring longer words.
+func searchTermsPattern(text string) *regexp.Regexp {
+	terms := strings.Fields(text)
+	sort.Slice(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
+	for i, term := range terms {
+		terms[i] = regexp.QuoteMeta(term)
+	}
+	return regexp.MustCompile("(?i)" + strings.Join(terms, "|"))
+}
+
+// highlight returns the HTML escaped value with the matches of the pattern wrapped in <em> tags,
+// or an empty string if nothing matches.
+func highlight(value string, pattern *regexp.Regexp) string {
+	matches := pattern.FindAllStringIndex(value, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	var highlighted strings.Builder
+	last := 0
+	for _, match := range matches {
+		highlighted.WriteString(html.EscapeString(value[last:match[0]]))
+		highlighted.WriteString("<em>" + html.EscapeString(value[match[0]:match[1]]) + "</em>")
+		last = match[1]
+	}
+	highlighted.WriteString(html.EscapeString(value[last:]))
+	return highlighted.String()
+}
+
+// NewSearchResult returns the result of the bank with loaded associations, highlighting the words of the searched
+// text in the bank name, address and town. Banks matched only by similar words have no highlights.
+func NewSearchResult(bank Bank, rank float64, text string) SearchResult {
+	pattern := searchTermsPattern(text)
+	result := SearchResult{Bank: bank, Rank: rank, Highlights: map[string]string{}}
+	for field, value := range map[string]string{
+		"bankName": bank.Name.Name,
+		"address":  bank.Address.Address,
+		"townName": bank.Address.Town.Town,
+	} {
+		if highlighted := highlight(value, pattern); highlighted != "" {
+			result.Highlights[field] = highlighted
+		}
+	}
+	return result
+}
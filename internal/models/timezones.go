@@ -0,0 +1,99 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"log"
+	"strings"
+	"time"
+	_ "time/tzdata"
+)
+
+// zone1970Data holds the IANA tz database table of time zones and the countries overlapping them.
+//
+//go:embed data/zone1970.tab
+var zone1970Data []byte
+
+// backwardData holds the links of the IANA tz database from former time zones to the zones of zone1970.tab.
+//
+//go:embed data/backward
+var backwardData []byte
+
+var (
+	countryTimeZones = loadCountryTimeZones()
+	timeZoneLinks    = loadTimeZoneLinks()
+)
+
+// loadCountryTimeZones parses the embedded zone1970.tab into a map of ISO2 code -> time zone names.
+func loadCountryTimeZones() map[string][]string {
+	zones := make(map[string][]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(zone1970Data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		columns := strings.Split(line, "\t")
+		if len(columns) < 3 {
+			continue
+		}
+		for _, iso2Code := range strings.Split(columns[0], ",") {
+			zones[iso2Code] = append(zones[iso2Code], columns[2])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("failed to parse embedded zone1970.tab data: %v", err)
+	}
+	return zones
+}
+
+// loadTimeZoneLinks parses the embedded backward links into a map of link name -> time zone name.
+func loadTimeZoneLinks() map[string]string {
+	links := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(backwardData))
+	for scanner.Scan() {
+		columns := strings.Split(scanner.Text(), "\t")
+		if len(columns) < 3 || columns[0] != "Link" {
+			continue
+		}
+		links[columns[2]] = columns[1]
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("failed to parse embedded backward data: %v", err)
+	}
+	return links
+}
+
+// isTimeZoneOfCountry reports whether the time zone belongs to the country.
+// The time zone belongs to the country if zone1970.tab lists it for the country, or if it is a link to a listed zone,
+// which covers aliases like Europe/Monaco (Europe/Paris) or America/Aruba (America/Puerto_Rico).
+// Countries missing from zone1970.tab are not checked.
+func isTimeZoneOfCountry(location *time.Location, iso2Code string) bool {
+	zones, ok := countryTimeZones[iso2Code]
+	if !ok {
+		return true
+	}
+
+	name := location.String()
+	if zone, ok := timeZoneLinks[name]; ok {
+		name = zone
+	}
+	for _, zone := range zones {
+		if zone == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultTimeZone returns the time zone of the country if zone1970.tab lists a single time zone for it,
+// otherwise an empty string.
+func DefaultTimeZone(ISO2Code string) string {
+	if zones := countryTimeZones[ISO2Code]; len(zones) == 1 {
+		return zones[0]
+	}
+	return ""
+}
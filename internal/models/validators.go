@@ -1,6 +1,9 @@
 package models
 
-import "strings"
+import (
+	"strings"
+	"time"
+)
 
 func checkForValidationError(details []string, message string) error {
 	if len(details) > 0 {
@@ -9,20 +12,106 @@ func checkForValidationError(details []string, message string) error {
 	return nil
 }
 
+func isUpperLetters(s string) bool {
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isUpperAlphanumeric(s string) bool {
+	for _, r := range s {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// validateSWIFTStructure checks the ISO 9362 segments of an 8 or 11 characters long SWIFT code:
+// 4 letters institution code, 2 letters country code, 2 alphanumeric location code and optional 3 alphanumeric branch code.
+// Case is validated separately, so segments are checked on the uppercased code.
+func validateSWIFTStructure(SWIFTCode string) []string {
+	var details []string
+	code := strings.ToUpper(SWIFTCode)
+
+	if !isUpperLetters(code[:4]) {
+		details = append(details, "SWIFT code institution code must consist of 4 letters")
+	}
+
+	countryCode := code[4:6]
+	if !isUpperLetters(countryCode) {
+		details = append(details, "SWIFT code country code must consist of 2 letters")
+	} else if !isISO3166Alpha2(countryCode) {
+		details = append(details, "SWIFT code country code must be a valid ISO 3166-1 alpha-2 code")
+	}
+
+	// test BICs, passive participants and reverse billing codes ('0', '1' or '2' as the second character) are valid
+	locationCode := code[6:8]
+	if !isUpperAlphanumeric(locationCode) {
+		details = append(details, "SWIFT code location code must consist of 2 letters or digits")
+	} else {
+		if locationCode[0] == '0' || locationCode[0] == '1' {
+			details = append(details, "SWIFT code location code cannot start with 0 or 1")
+		}
+		if locationCode[1] == 'O' {
+			details = append(details, "SWIFT code location code cannot end with letter O")
+		}
+	}
+
+	if len(code) == 11 {
+		branchCode := code[8:]
+		if !isUpperAlphanumeric(branchCode) {
+			details = append(details, "SWIFT code branch code must consist of 3 letters or digits")
+		} else if branchCode[0] == 'X' && branchCode != "XXX" {
+			details = append(details, "SWIFT code branch code cannot start with X unless it is XXX")
+		}
+	}
+
+	return details
+}
+
 func ValidateSWIFTCode(SWIFTCode string) error {
 	var details []string
 
-	if len(SWIFTCode) != 11 {
-		details = append(details, "SWIFT code must be 11 characters long")
+	correctLength := len(SWIFTCode) == 8 || len(SWIFTCode) == 11
+	if !correctLength {
+		details = append(details, "SWIFT code must be 8 or 11 characters long")
 	}
 
 	if strings.ToUpper(SWIFTCode) != SWIFTCode {
 		details = append(details, "SWIFT code must be in uppercase")
 	}
 
+	if correctLength {
+		details = append(details, validateSWIFTStructure(SWIFTCode)...)
+	}
+
 	return checkForValidationError(details, "Invalid SWIFT code")
 }
 
+// ValidateInstitutionCode checks the first 8 characters of a SWIFT code (BIC8), shared by a headquarter and its branches.
+func ValidateInstitutionCode(institutionCode string) error {
+	var details []string
+
+	correctLength := len(institutionCode) == 8
+	if !correctLength {
+		details = append(details, "Institution code must be 8 characters long")
+	}
+
+	if strings.ToUpper(institutionCode) != institutionCode {
+		details = append(details, "Institution code must be in uppercase")
+	}
+
+	if correctLength {
+		details = append(details, validateSWIFTStructure(institutionCode)...)
+	}
+
+	return checkForValidationError(details, "Invalid institution code")
+}
+
 func ValidateISO2Code(ISO2Code string) error {
 	var details []string
 
@@ -34,6 +123,10 @@ func ValidateISO2Code(ISO2Code string) error {
 		details = append(details, "ISO2 code must be in uppercase")
 	}
 
+	if len(ISO2Code) == 2 && !isISO3166Alpha2(strings.ToUpper(ISO2Code)) {
+		details = append(details, "ISO2 code must be a valid ISO 3166-1 alpha-2 code")
+	}
+
 	return checkForValidationError(details, "Invalid ISO2 code")
 }
 
@@ -48,9 +141,26 @@ func ValidateCountryName(CountryName string) error {
 		details = append(details, "Country name must be in uppercase")
 	}
 
+	if len(CountryName) != 0 && !isISO3166CountryName(strings.ToUpper(CountryName)) {
+		details = append(details, "Country name must be a valid ISO 3166-1 country name")
+	}
+
 	return checkForValidationError(details, "Invalid country name")
 }
 
+// ValidateCountry checks that the country name belongs to the country with the ISO2 code.
+// Unknown ISO2 codes and country names are reported by ValidateISO2Code and ValidateCountryName.
+func ValidateCountry(ISO2Code string, CountryName string) error {
+	var details []string
+
+	code, ok := countryCodesByName[strings.ToUpper(CountryName)]
+	if ok && isISO3166Alpha2(strings.ToUpper(ISO2Code)) && code != strings.ToUpper(ISO2Code) {
+		details = append(details, "Country name does not match ISO2 code")
+	}
+
+	return checkForValidationError(details, "Invalid country")
+}
+
 func ValidateBankName(Name string) error {
 	var details []string
 
@@ -61,12 +171,75 @@ func ValidateBankName(Name string) error {
 	return checkForValidationError(details, "Invalid bank name")
 }
 
+// ValidateSWIFTCountry checks that the country code of the SWIFT code (characters 5-6) matches the ISO2 code,
+// taking into account territories which use SWIFT codes of another country.
+func ValidateSWIFTCountry(SWIFTCode string, ISO2Code string) error {
+	var details []string
+
+	if len(SWIFTCode) >= 6 && !isSWIFTCountryAllowed(SWIFTCode[4:6], ISO2Code) {
+		details = append(details, "SWIFT code country code does not match ISO2 code")
+	}
+
+	return checkForValidationError(details, "Invalid SWIFT code country")
+}
+
 func ValidateHeadquarter(SWIFTCode string, isHeadquarter bool) error {
 	var details []string
 
-	if isHeadquarter != strings.HasSuffix(strings.ToUpper(SWIFTCode), "XXX") {
+	if isHeadquarter != strings.HasSuffix(strings.ToUpper(NormalizeSWIFTCode(SWIFTCode)), "XXX") {
 		details = append(details, "Headquarter status does not match SWIFT code")
 	}
 
 	return checkForValidationError(details, "Invalid headquarter status")
 }
+
+// ValidateCodeType checks that the code type is BIC8 or BIC11 and matches the length of the SWIFT code.
+// Invalid SWIFT codes are reported by ValidateSWIFTCode.
+func ValidateCodeType(CodeType string, SWIFTCode string) error {
+	var details []string
+
+	if CodeType != codeTypeBIC8 && CodeType != codeTypeBIC11 {
+		details = append(details, "Code type must be BIC8 or BIC11")
+	} else if codeType := SWIFTCodeType(SWIFTCode); codeType != "" && codeType != CodeType {
+		details = append(details, "Code type does not match SWIFT code")
+	}
+
+	return checkForValidationError(details, "Invalid code type")
+}
+
+// ValidateTownName checks the optional town name, an empty name is valid.
+func ValidateTownName(TownName string) error {
+	var details []string
+
+	if strings.TrimSpace(TownName) != TownName {
+		details = append(details, "Town name cannot start or end with whitespace")
+	}
+
+	return checkForValidationError(details, "Invalid town name")
+}
+
+func ValidateTimeZone(TimeZone string) error {
+	var details []string
+
+	if len(TimeZone) == 0 {
+		details = append(details, "Time zone cannot be empty")
+	} else if _, err := time.LoadLocation(TimeZone); err != nil || TimeZone == "Local" {
+		details = append(details, "Time zone must be a valid IANA time zone")
+	}
+
+	return checkForValidationError(details, "Invalid time zone")
+}
+
+// ValidateTimeZoneCountry checks that the time zone belongs to the country with the ISO2 code.
+// Invalid time zones are reported by ValidateTimeZone.
+func ValidateTimeZoneCountry(TimeZone string, ISO2Code string) error {
+	var details []string
+
+	if location, err := time.LoadLocation(TimeZone); err == nil && TimeZone != "" && TimeZone != "Local" {
+		if !isTimeZoneOfCountry(location, ISO2Code) {
+			details = append(details, "Time zone does not belong to the country")
+		}
+	}
+
+	return checkForValidationError(details, "Invalid time zone")
+}
@@ -0,0 +1,57 @@
+package models
+
+import "fmt"
+
+// BankView selects the fields of the JSON representation of banks.
+type BankView string
+
+const (
+	// BankViewDefault lists the fields of the bank required by the API
+	BankViewDefault BankView = ""
+	// BankViewFull adds the code type, town name and time zone of the bank
+	BankViewFull BankView = "full"
+)
+
+// ParseBankView converts the view query parameter to a BankView, an empty parameter means the default view.
+// It returns ErrRequestInvalid if the view is unknown.
+func ParseBankView(name string) (BankView, error) {
+	switch view := BankView(name); view {
+	case BankViewDefault, BankViewFull:
+		return view, nil
+	default:
+		return "", &ErrRequestInvalid{Message: "Request invalid", Details: []string{fmt.Sprintf("view must be %s", BankViewFull)}}
+	}
+}
+
+// SetView selects the view of the banks of the country.
+func (c *CountrySWIFTCode) SetView(view BankView) {
+	for i := range c.Banks {
+		c.Banks[i].View = view
+	}
+}
+
+// SetView selects the view of the headquarter and the branches of the institution.
+func (i *Institution) SetView(view BankView) {
+	if i.Headquarter != nil {
+		i.Headquarter.View = view
+	}
+	for j := range i.Branches {
+		i.Branches[j].View = view
+	}
+}
+
+// SetView selects the view of the banks of the search results.
+func (r *SearchResponse) SetView(view BankView) {
+	for i := range r.Results {
+		r.Results[i].Bank.View = view
+	}
+}
+
+// SetView selects the view of the found banks.
+func (r *LookupResponse) SetView(view BankView) {
+	for _, result := range r.Results {
+		if result.Bank != nil {
+			result.Bank.View = view
+		}
+	}
+}
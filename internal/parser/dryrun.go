@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"SWIFT-Remitly/internal/database"
+	"SWIFT-Remitly/internal/models"
+	"fmt"
+	"log"
+	"sort"
+)
+
+// checkRows adds to the report what storing the rows would do, without modifying the database.
+// Rows failing validation are rejected, the remaining rows are compared with the stored bank data,
+// or with an earlier row repeating their SWIFT code, which the import would store first. Branches whose
+// headquarter is neither among the valid rows nor stored are listed as orphans.
+func checkRows(db database.Service, rows []parsedRow, report *models.ImportReport) error {
+	imported := make(map[string]bool, len(rows))
+	validRows := make([]parsedRow, 0, len(rows))
+	for _, row := range rows {
+		if err := row.request.ValidateForStorage(); err != nil {
+			report.Reject(row.line, row.record, err)
+			continue
+		}
+		imported[row.request.SWIFTCode] = true
+		validRows = append(validRows, row)
+	}
+
+	// headquarters of the branches are retrieved together with the banks of the rows
+	swiftCodes := make([]string, 0, len(validRows))
+	for _, row := range validRows {
+		swiftCodes = append(swiftCodes, row.request.SWIFTCode)
+		if headquarterCode := row.request.SWIFTCode[:8] + "XXX"; headquarterCode != row.request.SWIFTCode {
+			swiftCodes = append(swiftCodes, headquarterCode)
+		}
+	}
+	storedBanks, err := db.GetBanksBySwiftCodes(swiftCodes)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve stored bank data: %w", err)
+	}
+
+	// earlier rows of the file are stored by the import before the rows repeating their SWIFT codes
+	earlierRows := make(map[string]models.CreateBankRequest, len(validRows))
+	for _, row := range validRows {
+		request := row.request
+		// stored country names are canonical, the row may use an alias
+		if countryName, err := models.CanonicalCountryName(request.ISO2Code, request.CountryName); err == nil {
+			request.CountryName = countryName
+		}
+
+		current, ok := earlierRows[request.SWIFTCode]
+		if !ok {
+			var storedBank models.Bank
+			if storedBank, ok = storedBanks[request.SWIFTCode]; ok {
+				current = storedBank.ToCreateBankRequest()
+			}
+		}
+		switch {
+		case !ok:
+			report.Add(models.UpsertInserted)
+		case request.SameBankData(current):
+			report.Add(models.UpsertUnchanged)
+		default:
+			report.Add(models.UpsertUpdated)
+		}
+		earlierRows[request.SWIFTCode] = request
+
+		headquarterCode := request.SWIFTCode[:8] + "XXX"
+		if headquarterCode == request.SWIFTCode {
+			continue
+		}
+		if _, stored := storedBanks[headquarterCode]; !imported[headquarterCode] && !stored {
+			log.Printf("Branch %s in CSV line %d would have no headquarter", request.SWIFTCode, row.line)
+			report.OrphanBranches = append(report.OrphanBranches, request.SWIFTCode)
+		}
+	}
+	sort.Strings(report.OrphanBranches)
+	return nil
+}
@@ -0,0 +1,161 @@
+package parser
+
+import (
+	"SWIFT-Remitly/internal/database"
+	"SWIFT-Remitly/internal/models"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseExportFormat converts the format name, e.g. from a query parameter, to the Format of an export.
+// An empty name means CSV, XLSX files cannot be exported.
+func ParseExportFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(name)); format {
+	case "":
+		return FormatCSV, nil
+	case FormatCSV, FormatNDJSON, FormatJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown export format %q, expected csv, ndjson or json", name)
+	}
+}
+
+// exportEncoder writes bank data in the format of an import file.
+type exportEncoder interface {
+	encode(request models.CreateBankRequest) error
+	// flush writes the buffered data to the underlying writer
+	flush() error
+	// close writes the end of the file, including the start of the file if no bank was encoded
+	close() error
+}
+
+// flusher is implemented by writers which buffer the written data, like HTTP responses.
+type flusher interface {
+	Flush()
+}
+
+// Export writes the bank data of the country, or of all countries if the ISO2 code is empty,
+// to the writer in a file format accepted by ParseCSV, so the exported file can be imported again.
+// The data is retrieved and written in batches, writers implementing Flush are flushed after every batch.
+// Nothing is written if the country is not stored.
+func Export(db database.Service, w io.Writer, format Format, iso2Code string) error {
+	var encoder exportEncoder
+	switch format {
+	case FormatCSV:
+		encoder = &csvExportEncoder{writer: csv.NewWriter(w)}
+	case FormatNDJSON:
+		encoder = &ndjsonExportEncoder{encoder: json.NewEncoder(w)}
+	case FormatJSON:
+		encoder = &jsonExportEncoder{writer: w}
+	default:
+		return fmt.Errorf("unsupported export format %q", format)
+	}
+
+	err := db.ExportBanks(iso2Code, func(banks []models.Bank) error {
+		for i := range banks {
+			if err := encoder.encode(banks[i].ToCreateBankRequest()); err != nil {
+				return fmt.Errorf("failed to write bank %s: %w", banks[i].SWIFTCode, err)
+			}
+		}
+		if err := encoder.flush(); err != nil {
+			return fmt.Errorf("failed to write banks: %w", err)
+		}
+		if f, ok := w.(flusher); ok {
+			f.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := encoder.close(); err != nil {
+		return fmt.Errorf("failed to write end of export: %w", err)
+	}
+	return nil
+}
+
+// csvExportEncoder writes the expected headers followed by a row of every bank.
+type csvExportEncoder struct {
+	writer  *csv.Writer
+	started bool
+}
+
+func (e *csvExportEncoder) writeHeader() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+	return e.writer.Write(correctHeaders)
+}
+
+func (e *csvExportEncoder) encode(request models.CreateBankRequest) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	return e.writer.Write(newJSONRecord(request).record())
+}
+
+func (e *csvExportEncoder) flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvExportEncoder) close() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	return e.flush()
+}
+
+// ndjsonExportEncoder writes a JSON object of every bank in a separate line.
+type ndjsonExportEncoder struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonExportEncoder) encode(request models.CreateBankRequest) error {
+	return e.encoder.Encode(newJSONRecord(request))
+}
+
+func (e *ndjsonExportEncoder) flush() error {
+	return nil
+}
+
+func (e *ndjsonExportEncoder) close() error {
+	return nil
+}
+
+// jsonExportEncoder writes a JSON array of bank objects, one object per line.
+type jsonExportEncoder struct {
+	writer  io.Writer
+	started bool
+}
+
+func (e *jsonExportEncoder) encode(request models.CreateBankRequest) error {
+	data, err := json.Marshal(newJSONRecord(request))
+	if err != nil {
+		return err
+	}
+	separator := ",\n"
+	if !e.started {
+		separator = "[\n"
+		e.started = true
+	}
+	_, err = e.writer.Write(append([]byte(separator), data...))
+	return err
+}
+
+func (e *jsonExportEncoder) flush() error {
+	return nil
+}
+
+func (e *jsonExportEncoder) close() error {
+	end := "\n]\n"
+	if !e.started {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.writer, end)
+	return err
+}
@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// defaultHeaderAliases maps header names used by other data providers to the expected headers.
+var defaultHeaderAliases = map[string]string{
+	"ISO2 CODE":    "COUNTRY ISO2 CODE",
+	"COUNTRY CODE": "COUNTRY ISO2 CODE",
+	"BIC":          "SWIFT CODE",
+	"BIC CODE":     "SWIFT CODE",
+	"SWIFT":        "SWIFT CODE",
+	"BANK NAME":    "NAME",
+	"CITY":         "TOWN NAME",
+	"TOWN":         "TOWN NAME",
+	"COUNTRY":      "COUNTRY NAME",
+	"TIMEZONE":     "TIME ZONE",
+}
+
+// ParseHeaderAliases converts a comma separated list of alias:header pairs, e.g. from an environment variable,
+// to a map of aliases by their uppercased names, e.g. "BIC:SWIFT CODE,CITY:TOWN NAME".
+func ParseHeaderAliases(list string) (map[string]string, error) {
+	aliases := map[string]string{}
+	if strings.TrimSpace(list) == "" {
+		return aliases, nil
+	}
+	for _, pair := range strings.Split(list, ",") {
+		alias, header, found := strings.Cut(pair, ":")
+		alias, header = normalizeHeader(alias), normalizeHeader(header)
+		if !found || alias == "" {
+			return nil, fmt.Errorf("header alias %q must have the form <alias>:<header>", pair)
+		}
+		if !isExpectedHeader(header) {
+			return nil, fmt.Errorf("header alias %q refers to unknown header %q", pair, header)
+		}
+		aliases[alias] = header
+	}
+	return aliases, nil
+}
+
+// ParseDelimiter converts the delimiter, e.g. from an environment variable, to the rune separating CSV fields.
+// An empty delimiter means the default comma, "tab" and "\t" mean the tab character.
+func ParseDelimiter(name string) (rune, error) {
+	switch name {
+	case "":
+		return ',', nil
+	case "tab", `\t`:
+		return '\t', nil
+	}
+	delimiter, size := utf8.DecodeRuneInString(name)
+	if size != len(name) || delimiter == utf8.RuneError || strings.ContainsRune("\"\r\n", delimiter) {
+		return 0, fmt.Errorf("invalid CSV delimiter %q, expected a single character", name)
+	}
+	return delimiter, nil
+}
+
+// ParseEncoding converts the encoding name, e.g. utf-8, utf-16le, iso-8859-2 or windows-1250, to an encoding.
+// An empty name means UTF-8.
+func ParseEncoding(name string) (encoding.Encoding, error) {
+	if name == "" {
+		return unicode.UTF8, nil
+	}
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown CSV encoding %q", name)
+	}
+	return enc, nil
+}
+
+// normalizeHeader trims and uppercases the header name.
+func normalizeHeader(header string) string {
+	return strings.ToUpper(strings.TrimSpace(header))
+}
+
+func isExpectedHeader(header string) bool {
+	for _, expected := range correctHeaders {
+		if header == expected {
+			return true
+		}
+	}
+	return false
+}
+
+// mapHeaders returns the headers of the file with names and aliases of the expected headers replaced by the
+// expected names, so the columns are decoded by name in any order. Other columns are kept unchanged and ignored.
+// It returns an error listing the expected headers which are missing or provided more than once.
+func mapHeaders(headers []string, aliases map[string]string) ([]string, error) {
+	var details []string
+	mapped := make([]string, len(headers))
+	seen := make(map[string]bool, len(correctHeaders))
+	for i, header := range headers {
+		name := normalizeHeader(header)
+		if alias, ok := aliases[name]; ok {
+			name = alias
+		}
+		if !isExpectedHeader(name) {
+			mapped[i] = header
+			continue
+		}
+		if seen[name] {
+			details = append(details, fmt.Sprintf("column %s is provided more than once", name))
+		}
+		seen[name] = true
+		mapped[i] = name
+	}
+	for _, header := range correctHeaders {
+		if !seen[header] {
+			details = append(details, fmt.Sprintf("column %s is missing", header))
+		}
+	}
+
+	if len(details) > 0 {
+		return nil, fmt.Errorf("CSV headers do not match expected headers: %s", strings.Join(details, ", "))
+	}
+	return mapped, nil
+}
+
+// newContentReader returns a reader of the content decoded from the configured encoding to UTF-8.
+// A byte order mark at the beginning of the content takes precedence over the configured encoding and is removed.
+func newContentReader(content io.Reader, config options) io.Reader {
+	enc := config.encoding
+	if enc == nil {
+		enc = unicode.UTF8
+	}
+	return transform.NewReader(content, unicode.BOMOverride(enc.NewDecoder()))
+}
+
+// newCSVReader returns a CSV reader of the content decoded from the configured encoding, using the configured delimiter.
+func newCSVReader(content io.Reader, config options) *csv.Reader {
+	reader := csv.NewReader(newContentReader(content, config))
+	if config.delimiter != 0 {
+		reader.Comma = config.delimiter
+	}
+	return reader
+}
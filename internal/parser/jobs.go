@@ -0,0 +1,192 @@
+package parser
+
+import (
+	"SWIFT-Remitly/internal/database"
+	"SWIFT-Remitly/internal/models"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// finishedJobsRetention is how long finished jobs are kept, so clients can fetch their reports.
+	finishedJobsRetention = 24 * time.Hour
+	// maxFinishedJobs is the number of finished jobs kept, the oldest are removed first.
+	maxFinishedJobs = 100
+)
+
+// ImportJobs runs imports of uploaded files in the background and keeps their state in memory.
+// Finished jobs are kept for finishedJobsRetention, at most maxFinishedJobs of them.
+type ImportJobs struct {
+	db       database.Service
+	defaults []Option
+	mutex    sync.RWMutex
+	jobs     map[string]*models.ImportJob
+	// finished are the IDs of the finished jobs, oldest first
+	finished []string
+}
+
+// NewImportJobs creates an empty set of import jobs storing the bank data in the database.
+// The default options are applied to every job before the options of the job.
+func NewImportJobs(db database.Service, defaults ...Option) *ImportJobs {
+	return &ImportJobs{db: db, defaults: defaults, jobs: map[string]*models.ImportJob{}}
+}
+
+// newJobID returns a random 32 characters long hexadecimal job ID.
+func newJobID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// countRows returns the number of rows of the source.
+func countRows(source Source) (int, error) {
+	rows := 0
+	for {
+		_, err := source.Next()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		rows++
+	}
+}
+
+// storeUpload writes the uploaded content to a temporary file with the extension of the uploaded file
+// and validates its headers. It returns the path of the file and the number of its rows.
+func storeUpload(fileName string, content io.Reader, config options) (string, int, error) {
+	file, err := os.CreateTemp("", "import-*"+filepath.Ext(fileName))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create import file: %w", err)
+	}
+	defer file.Close()
+
+	rows, err := func() (int, error) {
+		if _, err := io.Copy(file, content); err != nil {
+			return 0, fmt.Errorf("failed to write import file: %w", err)
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return 0, fmt.Errorf("failed to reset file pointer: %w", err)
+		}
+		source, err := openSource(file, fileName, config)
+		if err != nil {
+			return 0, &models.ErrRequestInvalid{Message: "Invalid import file", Details: []string{err.Error()}}
+		}
+		rows, err := countRows(source)
+		if err != nil {
+			return 0, &models.ErrRequestInvalid{Message: "Invalid import file", Details: []string{err.Error()}}
+		}
+		return rows, nil
+	}()
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return "", 0, err
+	}
+	return file.Name(), rows, nil
+}
+
+// Start validates the uploaded file and imports it in the background with the options.
+// The format of the file is detected from the extension of the file name, unless set by WithFormat.
+// It returns the started job, or ErrRequestInvalid if the file cannot be read.
+func (j *ImportJobs) Start(fileName string, content io.Reader, opts ...Option) (models.ImportJob, error) {
+	id, err := newJobID()
+	if err != nil {
+		return models.ImportJob{}, fmt.Errorf("failed to create import job ID: %w", err)
+	}
+	opts = append(append([]Option(nil), j.defaults...), opts...)
+	csvDataPath, totalRows, err := storeUpload(fileName, content, newOptions(opts))
+	if err != nil {
+		return models.ImportJob{}, err
+	}
+
+	job := &models.ImportJob{
+		ID:        id,
+		FileName:  fileName,
+		Status:    models.ImportRunning,
+		TotalRows: totalRows,
+		CreatedAt: time.Now(),
+	}
+	j.mutex.Lock()
+	j.removeFinished(time.Now())
+	j.jobs[id] = job
+	started := *job
+	j.mutex.Unlock()
+
+	log.Printf("Started import job %s of file %s", id, fileName)
+	go j.run(id, csvDataPath, opts)
+	return started, nil
+}
+
+// run imports the file of the job, updating its progress, and removes the file when the import finishes.
+func (j *ImportJobs) run(id string, csvDataPath string, opts []Option) {
+	defer func() {
+		if err := os.Remove(csvDataPath); err != nil {
+			log.Printf("failed to remove import file: %v", err)
+		}
+	}()
+
+	progress := WithProgress(func(summary models.ImportSummary) {
+		j.mutex.Lock()
+		defer j.mutex.Unlock()
+		j.jobs[id].Progress = summary
+	})
+	report, err := ParseCSVContext(context.Background(), j.db, csvDataPath, append(opts, progress)...)
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	job := j.jobs[id]
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	job.Progress = report.ImportSummary
+	job.Report = &report
+	job.Status = models.ImportCompleted
+	j.finished = append(j.finished, id)
+	j.removeFinished(finishedAt)
+	if err != nil {
+		log.Printf("Import job %s failed: %v", id, err)
+		job.Status = models.ImportFailed
+		job.Error = err.Error()
+		return
+	}
+	log.Printf("Import job %s completed", id)
+}
+
+// removeFinished removes the finished jobs which are kept longer than finishedJobsRetention at the time,
+// and the oldest finished jobs beyond maxFinishedJobs. The caller must hold the write lock.
+func (j *ImportJobs) removeFinished(now time.Time) {
+	removed := 0
+	for _, id := range j.finished {
+		if len(j.finished)-removed <= maxFinishedJobs && now.Sub(*j.jobs[id].FinishedAt) < finishedJobsRetention {
+			break
+		}
+		delete(j.jobs, id)
+		removed++
+	}
+	j.finished = j.finished[removed:]
+}
+
+// Get returns a copy of the job with the ID, wrapping gorm.ErrRecordNotFound if there is no such job.
+func (j *ImportJobs) Get(id string) (models.ImportJob, error) {
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+
+	job, ok := j.jobs[id]
+	if !ok {
+		return models.ImportJob{}, fmt.Errorf("import job %s: %w", id, gorm.ErrRecordNotFound)
+	}
+	return *job, nil
+}
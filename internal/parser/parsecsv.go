@@ -3,13 +3,13 @@ package parser
 import (
 	"SWIFT-Remitly/internal/database"
 	"SWIFT-Remitly/internal/models"
-	"encoding/csv"
+	"context"
+	"errors"
 	"fmt"
-	"github.com/jszwec/csvutil"
-	"io"
+	"golang.org/x/text/encoding"
 	"log"
 	"os"
-	"strings"
+	"sort"
 )
 
 var correctHeaders = []string{
@@ -23,88 +23,270 @@ var correctHeaders = []string{
 	"TIME ZONE",
 }
 
-func validateHeaders(headers []string) bool {
-	if len(headers) != len(correctHeaders) {
-		return false
+// Option configures ParseCSV.
+type Option func(*options)
+
+type options struct {
+	rejectsFormat RejectsFormat
+	bulk          bool
+	bulkMode      database.BulkMode
+	workers       int
+	progress      func(models.ImportSummary)
+	dryRun        bool
+	headerAliases map[string]string
+	delimiter     rune
+	encoding      encoding.Encoding
+	format        Format
+	sheet         string
+}
+
+// newOptions returns the default options with the options applied.
+func newOptions(opts []Option) options {
+	config := options{workers: 1, headerAliases: make(map[string]string, len(defaultHeaderAliases))}
+	for alias, header := range defaultHeaderAliases {
+		config.headerAliases[alias] = header
+	}
+	for _, opt := range opts {
+		opt(&config)
 	}
-	for i, header := range headers {
-		if !strings.EqualFold(header, correctHeaders[i]) {
-			return false
+	if config.bulk || config.dryRun {
+		config.workers = 1
+	}
+	if config.dryRun {
+		config.rejectsFormat = ""
+	}
+	return config
+}
+
+// WithHeaderAliases makes ParseCSV accept the aliases, e.g. from ParseHeaderAliases, in place of the expected headers,
+// in addition to the built-in aliases.
+func WithHeaderAliases(aliases map[string]string) Option {
+	return func(o *options) {
+		for alias, header := range aliases {
+			o.headerAliases[normalizeHeader(alias)] = header
 		}
 	}
+}
 
-	return true
+// WithDelimiter makes ParseCSV separate the fields with the delimiter instead of a comma.
+func WithDelimiter(delimiter rune) Option {
+	return func(o *options) {
+		o.delimiter = delimiter
+	}
 }
 
-func validateFile(file *os.File) (*csvutil.Decoder, error) {
-	fileInfo, err := file.Stat()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get file info: %w", err)
+// WithEncoding makes ParseCSV decode the file from the encoding instead of UTF-8.
+func WithEncoding(enc encoding.Encoding) Option {
+	return func(o *options) {
+		o.encoding = enc
 	}
-	if fileInfo.Size() == 0 {
-		return nil, fmt.Errorf("CSV file is empty")
+}
+
+// WithRejectsFile makes ParseCSV write the rejected rows to a JSON or CSV file placed next to the input file.
+// An empty format disables the rejects file.
+func WithRejectsFile(format RejectsFormat) Option {
+	return func(o *options) {
+		o.rejectsFormat = format
 	}
+}
 
-	csvReader := csv.NewReader(file)
+// WithBulkImport makes ParseCSV store all rows with a single bulk upsert after the file is read,
+// instead of storing every row in its own transaction.
+// In database.BulkAtomic mode no row is stored if any row is rejected.
+func WithBulkImport(mode database.BulkMode) Option {
+	return func(o *options) {
+		o.bulk = true
+		o.bulkMode = mode
+	}
+}
 
-	headers, err := csvReader.Read()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read CSV headers: %w", err)
+// WithWorkers makes ParseCSV store the rows with the given number of concurrent workers.
+// Banks of the same institution are always stored by the same worker in the order of the file.
+// The option is ignored by the bulk import, which stores all rows at once.
+func WithWorkers(workers int) Option {
+	return func(o *options) {
+		o.workers = max(workers, 1)
 	}
+}
 
-	if !validateHeaders(headers) {
-		return nil, fmt.Errorf("CSV headers do not match expected headers")
+// WithDryRun makes ParseCSV report what the import would do without storing any bank data.
+// Rows are validated and compared with the stored bank data, or with an earlier row repeating their SWIFT code,
+// which the import stores first. Branches whose headquarter would be missing are listed in the report.
+// The bulk import, workers and rejects file options are ignored in a dry run, which writes no files.
+func WithDryRun() Option {
+	return func(o *options) {
+		o.dryRun = true
 	}
+}
 
-	// Reset the file pointer to the beginning of the file
-	if _, err := file.Seek(0, io.SeekStart); err != nil {
-		return nil, fmt.Errorf("failed to reset file pointer: %w", err)
+// WithFormat makes ParseCSV read the file in the format instead of detecting it from the file extension.
+func WithFormat(format Format) Option {
+	return func(o *options) {
+		o.format = format
 	}
+}
 
-	decoder, err := csvutil.NewDecoder(csv.NewReader(file))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create CSV decoder: %w", err)
+// WithSheet makes ParseCSV read the sheet with the name from XLSX files instead of the first sheet.
+func WithSheet(name string) Option {
+	return func(o *options) {
+		o.sheet = name
+	}
+}
+
+// WithProgress makes ParseCSV call the function with the summary of the rows processed so far
+// every time a row is stored or rejected. The function is never called concurrently.
+func WithProgress(progress func(models.ImportSummary)) Option {
+	return func(o *options) {
+		o.progress = progress
+	}
+}
+
+// storeBulk stores the rows with a single bulk upsert and adds the results to the report.
+func storeBulk(db database.Service, rows []parsedRow, mode database.BulkMode, report *models.ImportReport) error {
+	if mode == database.BulkAtomic && report.Failed > 0 {
+		return fmt.Errorf("%w: %d rows are invalid", database.ErrBulkAborted, report.Failed)
+	}
+
+	requests := make([]models.CreateBankRequest, len(rows))
+	for i, row := range rows {
+		requests[i] = row.request
 	}
-	return decoder, nil
+	results, err := db.BulkUpsertBanks(requests, mode)
+	if err != nil && !errors.Is(err, database.ErrBulkAborted) {
+		return err
+	}
+	for i, result := range results {
+		if result.Err != nil {
+			log.Printf("failed to store bank from request in CSV line %d: %v", rows[i].line, result.Err)
+			report.Reject(rows[i].line, rows[i].record, result.Err)
+			continue
+		}
+		// in atomic mode nothing is stored if the bulk upsert was aborted
+		if err == nil {
+			report.Add(result.Result)
+		}
+	}
+	return err
+}
+
+// ParseCSV reads a CSV, NDJSON, JSON or XLSX file row by row and adds or updates the bank data in the database.
+// The format is detected from the file extension, unless set by WithFormat. Importing the same file again leaves
+// the stored bank data unchanged.
+// It returns a report with the number of inserted, updated, unchanged and failed rows together with the line number,
+// raw record and errors of every row which cannot be decoded or stored, and an error if the file cannot be read
+// or the rejects file cannot be written.
+func ParseCSV(db database.Service, csvDataPath string, opts ...Option) (models.ImportReport, error) {
+	return ParseCSVContext(context.Background(), db, csvDataPath, opts...)
 }
 
-// ParseCSV reads a CSV file and adds the bank data to the database.
-// It returns an error if the CSV file cannot be read
-// Reads a CSV file line by line, logs if there is an error in decoding the line
-// Logs if there is an error in adding the bank to the database
-func ParseCSV(db database.Service, csvDataPath string) error {
-	log.Println(fmt.Sprintf("Started parsing CSV data from file: %s", csvDataPath))
+// ParseCSVContext is ParseCSV with a context, see ParseSource.
+func ParseCSVContext(ctx context.Context, db database.Service, csvDataPath string, opts ...Option) (models.ImportReport, error) {
+	log.Println(fmt.Sprintf("Started parsing data from file: %s", csvDataPath))
+
+	config := newOptions(opts)
 
+	report := models.ImportReport{Rejected: []models.RejectedRow{}, DryRun: config.dryRun}
 	file, err := os.OpenFile(csvDataPath, os.O_RDONLY, os.ModePerm)
 	if err != nil {
-		return fmt.Errorf("failed to open CSV file: %w", err)
+		return report, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer func() {
 		if err := file.Close(); err != nil {
-			log.Printf("failed to close CSV file: %v", err)
+			log.Printf("failed to close file: %v", err)
 		}
 	}()
 
-	decoder, err := validateFile(file)
+	source, err := openSource(file, csvDataPath, config)
 	if err != nil {
-		return fmt.Errorf("during CSV validation got: %w", err)
+		return report, fmt.Errorf("during file validation got: %w", err)
 	}
 
-	// Read and process each line
-	for {
-		var bank models.CreateBankRequest
-		if err := decoder.Decode(&bank); err != nil {
-			if err.Error() == "EOF" {
-				break
+	report, importErr := parseSource(ctx, db, source, config)
+
+	if config.rejectsFormat != "" && len(report.Rejected) > 0 {
+		rejectsPath := rejectsFilePath(csvDataPath, config.rejectsFormat)
+		if err := writeRejectsFile(rejectsPath, config.rejectsFormat, source.Header(), report.Rejected); err != nil {
+			return report, err
+		}
+		report.RejectsFile = rejectsPath
+		log.Printf("Rejected rows written to file: %s", rejectsPath)
+	}
+	return report, importErr
+}
+
+// ParseSource adds or updates the bank data of the rows of the source in the database, like ParseCSV.
+// The rejects file option is ignored, as the source has no path.
+// Decoding stops when the context is canceled, rows already passed to the workers are finished
+// and the report of the stored rows is returned with the context error.
+// The source is read by a separate goroutine feeding the workers through bounded channels.
+func ParseSource(ctx context.Context, db database.Service, source Source, opts ...Option) (models.ImportReport, error) {
+	return parseSource(ctx, db, source, newOptions(opts))
+}
+
+func parseSource(ctx context.Context, db database.Service, source Source, config options) (models.ImportReport, error) {
+	report := models.ImportReport{Rejected: []models.RejectedRow{}, DryRun: config.dryRun}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	shards := make([]chan parsedRow, config.workers)
+	for i := range shards {
+		shards[i] = make(chan parsedRow, rowBufferSize)
+	}
+	decodeErrs := make(chan error, 1)
+	go func() {
+		decodeErrs <- decodeRows(ctx, source, shards)
+	}()
+
+	var decodeErr, storeErr error
+	switch {
+	case config.dryRun:
+		rows := collectRows(shards[0], &report)
+		decodeErr = <-decodeErrs
+		if ctx.Err() == nil && decodeErr == nil {
+			if err := checkRows(db, rows, &report); err != nil {
+				storeErr = fmt.Errorf("during dry run got: %w", err)
 			}
-			log.Printf("failed to decode CSV line: %v", err)
-			continue
 		}
-		if err := db.AddBankFromRequest(bank); err != nil {
-			log.Printf("failed to add bank from request: %v", err)
+		if config.progress != nil {
+			config.progress(report.ImportSummary)
+		}
+	case config.bulk:
+		rows := collectRows(shards[0], &report)
+		decodeErr = <-decodeErrs
+		if ctx.Err() == nil && decodeErr == nil {
+			if err := storeBulk(db, rows, config.bulkMode, &report); err != nil {
+				storeErr = fmt.Errorf("during bulk import got: %w", err)
+			}
+		}
+		if config.progress != nil {
+			config.progress(report.ImportSummary)
 		}
+	default:
+		storeConcurrently(ctx, db, shards, &report, config.progress)
+		decodeErr = <-decodeErrs
+	}
+	// workers finish rows in any order
+	sort.SliceStable(report.Rejected, func(i, j int) bool {
+		return report.Rejected[i].Line < report.Rejected[j].Line
+	})
+
+	if config.dryRun {
+		log.Printf("Dry run finished: %d would be inserted, %d updated, %d unchanged, %d rejected, %d branches without headquarter",
+			report.Inserted, report.Updated, report.Unchanged, report.Failed, len(report.OrphanBranches))
+	} else {
+		log.Printf("Parsing finished: %d inserted, %d updated, %d unchanged, %d failed",
+			report.Inserted, report.Updated, report.Unchanged, report.Failed)
 	}
 
-	log.Println("Parsing finished")
-	return nil
+	if err := ctx.Err(); err != nil {
+		return report, fmt.Errorf("import canceled: %w", err)
+	}
+	if decodeErr != nil {
+		return report, fmt.Errorf("during reading file got: %w", decodeErr)
+	}
+	if storeErr != nil {
+		return report, storeErr
+	}
+	return report, nil
 }
@@ -0,0 +1,157 @@
+package parser
+
+import (
+	"SWIFT-Remitly/internal/database"
+	"SWIFT-Remitly/internal/models"
+	"context"
+	"errors"
+	"hash/fnv"
+	"io"
+	"log"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// rowBufferSize is the capacity of the channels between the pipeline stages.
+	rowBufferSize = 64
+	// maxStoreAttempts limits retries of rows conflicting with lookup table records created concurrently.
+	maxStoreAttempts = 3
+)
+
+// parsedRow is a decoded row of the file, err is set if the row was rejected while decoding.
+type parsedRow struct {
+	line    int
+	record  []string
+	request models.CreateBankRequest
+	err     error
+}
+
+// storedRow is the outcome of storing a parsed row in the database.
+type storedRow struct {
+	parsedRow
+	result models.UpsertResult
+}
+
+// shardIndex returns the shard of the SWIFT code. Banks of the same institution (same first 8 characters)
+// always share a shard, so a headquarter and its branches are stored by one worker in the order of the file,
+// and linking them never races with another worker.
+func shardIndex(swiftCode string, shards int) int {
+	if shards == 1 || len(swiftCode) < 8 {
+		return 0
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(swiftCode[:8]))
+	return int(hash.Sum32() % uint32(shards))
+}
+
+// decodeRows reads the rows of the source and sends them to the shard of their SWIFT code.
+// Rows which cannot be decoded or whose SWIFT code does not match the country are sent with an error.
+// It closes the shards when the source ends or the context is canceled, and returns the error
+// if the rest of the source cannot be read.
+func decodeRows(ctx context.Context, source Source, shards []chan parsedRow) error {
+	defer func() {
+		for _, shard := range shards {
+			close(shard)
+		}
+	}()
+
+	for {
+		sourceRow, err := source.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			log.Printf("failed to read file: %v", err)
+			return err
+		}
+		row := parsedRow{line: sourceRow.Line, record: sourceRow.Record, request: sourceRow.Request, err: sourceRow.Err}
+		line := row.line
+
+		if row.err != nil {
+			log.Printf("failed to decode line %d: %v", line, row.err)
+		} else {
+			row.request.Normalize()
+			if err := models.ValidateSWIFTCountry(row.request.SWIFTCode, row.request.ISO2Code); err != nil {
+				log.Printf("SWIFT code %s in line %d does not match country ISO2 code %s: %v", row.request.SWIFTCode, line, row.request.ISO2Code, err)
+				row.err = err
+			} else if row.request.TimeZone == "" {
+				log.Printf("Line %d has no time zone, bank %s will be added without it", line, row.request.SWIFTCode)
+			}
+		}
+
+		select {
+		case shards[shardIndex(row.request.SWIFTCode, len(shards))] <- row:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// storeRows upserts the rows of the shard one by one and sends the outcomes to the results channel.
+// Rows left in the shard after the context is canceled are skipped.
+func storeRows(ctx context.Context, db database.Service, shard <-chan parsedRow, results chan<- storedRow) {
+	for row := range shard {
+		if ctx.Err() != nil {
+			continue
+		}
+		stored := storedRow{parsedRow: row}
+		if row.err == nil {
+			for attempt := 1; attempt <= maxStoreAttempts; attempt++ {
+				stored.result, stored.err = db.UpsertBankFromRequest(row.request)
+				// another worker may have created the same town, name or address in the meantime
+				if !errors.Is(stored.err, gorm.ErrDuplicatedKey) {
+					break
+				}
+			}
+			if stored.err != nil {
+				log.Printf("failed to store bank from request in CSV line %d: %v", row.line, stored.err)
+			}
+		}
+		results <- stored
+	}
+}
+
+// storeConcurrently stores the rows of the shards with one worker per shard and adds the outcomes to the report.
+// The progress function, if any, is called with the summary after every outcome.
+func storeConcurrently(ctx context.Context, db database.Service, shards []chan parsedRow, report *models.ImportReport, progress func(models.ImportSummary)) {
+	results := make(chan storedRow, rowBufferSize)
+
+	var wg sync.WaitGroup
+	for _, shard := range shards {
+		wg.Add(1)
+		go func(shard <-chan parsedRow) {
+			defer wg.Done()
+			storeRows(ctx, db, shard, results)
+		}(shard)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for stored := range results {
+		if stored.err != nil {
+			report.Reject(stored.line, stored.record, stored.err)
+		} else {
+			report.Add(stored.result)
+		}
+		if progress != nil {
+			progress(report.ImportSummary)
+		}
+	}
+}
+
+// collectRows reads all rows of the shard, rejecting the ones with errors, and returns the valid ones.
+func collectRows(shard <-chan parsedRow, report *models.ImportReport) []parsedRow {
+	var rows []parsedRow
+	for row := range shard {
+		if row.err != nil {
+			report.Reject(row.line, row.record, row.err)
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
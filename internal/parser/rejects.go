@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"SWIFT-Remitly/internal/models"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RejectsFormat is the format of the file the rejected rows of an import are written to.
+type RejectsFormat string
+
+const (
+	RejectsJSON RejectsFormat = "json"
+	RejectsCSV  RejectsFormat = "csv"
+)
+
+// ParseRejectsFormat converts the format name, e.g. from an environment variable, to a RejectsFormat.
+// An empty name means that no rejects file is written.
+func ParseRejectsFormat(name string) (RejectsFormat, error) {
+	switch format := RejectsFormat(strings.ToLower(name)); format {
+	case "", RejectsJSON, RejectsCSV:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown rejects file format %q, expected json or csv", name)
+	}
+}
+
+// rejectsFilePath returns the path of the rejects file placed next to the input file,
+// e.g. data/banks.csv -> data/banks.rejects.json
+func rejectsFilePath(inputPath string, format RejectsFormat) string {
+	base := strings.TrimSuffix(inputPath, filepath.Ext(inputPath))
+	return base + ".rejects." + string(format)
+}
+
+// writeRejectsFile writes the rejected rows of the report to the file in the given format.
+// CSV rejects files contain the input headers followed by the LINE and ERRORS columns.
+func writeRejectsFile(path string, format RejectsFormat, headers []string, rejected []models.RejectedRow) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create rejects file: %w", err)
+	}
+	defer file.Close()
+
+	switch format {
+	case RejectsJSON:
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(rejected); err != nil {
+			return fmt.Errorf("failed to write rejects file: %w", err)
+		}
+	case RejectsCSV:
+		writer := csv.NewWriter(file)
+		if err := writer.Write(append(append([]string(nil), headers...), "LINE", "ERRORS")); err != nil {
+			return fmt.Errorf("failed to write rejects file: %w", err)
+		}
+		for _, row := range rejected {
+			// keep the columns aligned with the headers, even for rows with a wrong number of fields
+			record := make([]string, len(headers))
+			copy(record, row.Record)
+			errorsText := row.Error
+			if len(row.Details) > 0 {
+				errorsText += ": " + strings.Join(row.Details, "; ")
+			}
+			if err := writer.Write(append(record, strconv.Itoa(row.Line), errorsText)); err != nil {
+				return fmt.Errorf("failed to write rejects file: %w", err)
+			}
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return fmt.Errorf("failed to write rejects file: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown rejects file format %q", format)
+	}
+	return file.Close()
+}
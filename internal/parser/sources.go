@@ -0,0 +1,293 @@
+package parser
+
+import (
+	"SWIFT-Remitly/internal/models"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jszwec/csvutil"
+)
+
+// Row is a row of an import file decoded by a Source.
+type Row struct {
+	// Line is the line of a CSV or NDJSON file, the position of the record in a JSON array or the row of a sheet
+	Line    int
+	Record  []string
+	Request models.CreateBankRequest
+	// Err is set if the row cannot be decoded, the following rows are still read
+	Err error
+}
+
+// Source reads bank data rows from an import file.
+type Source interface {
+	// Header returns the names of the fields of the raw records, used as the columns of CSV rejects files.
+	Header() []string
+
+	// Next returns the next row of the file.
+	// It returns io.EOF after the last row, and any other error if the rest of the file cannot be read.
+	Next() (Row, error)
+}
+
+// Format is the format of an import file.
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+	FormatJSON   Format = "json"
+	FormatXLSX   Format = "xlsx"
+)
+
+// sourceOpeners creates the sources of the file formats from files positioned at their beginning.
+var sourceOpeners = map[Format]func(file *os.File, config options) (Source, error){
+	FormatCSV:    newCSVSource,
+	FormatNDJSON: newNDJSONSource,
+	FormatJSON:   newJSONSource,
+	FormatXLSX:   newXLSXSource,
+}
+
+// ParseFormat converts the format name, e.g. from an environment variable, to a Format.
+// An empty name means that the format is detected from the file extension.
+func ParseFormat(name string) (Format, error) {
+	format := Format(strings.ToLower(name))
+	if _, ok := sourceOpeners[format]; ok || format == "" {
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown import file format %q, expected csv, ndjson, json or xlsx", name)
+}
+
+// DetectFormat returns the format of the file based on its extension.
+// Files with unknown extensions are read as CSV files.
+func DetectFormat(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON
+	case ".json":
+		return FormatJSON
+	case ".xlsx":
+		return FormatXLSX
+	default:
+		return FormatCSV
+	}
+}
+
+// openSource checks that the file is not empty and returns the source of its format,
+// configured by the options or detected from the path.
+func openSource(file *os.File, path string, config options) (Source, error) {
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+	if fileInfo.Size() == 0 {
+		return nil, fmt.Errorf("file is empty")
+	}
+
+	format := config.format
+	if format == "" {
+		format = DetectFormat(path)
+	}
+	return sourceOpeners[format](file, config)
+}
+
+// errUnreadableTable is wrapped by the errors of table readers which cannot read the rest of the table.
+var errUnreadableTable = errors.New("table cannot be read")
+
+// tableReader reads the raw records of a table, counting its lines.
+type tableReader interface {
+	Read() ([]string, error)
+	Line() int
+}
+
+// csvLineReader counts the records read from a CSV reader.
+type csvLineReader struct {
+	*csv.Reader
+	line int
+}
+
+func (r *csvLineReader) Read() ([]string, error) {
+	r.line++
+	return r.Reader.Read()
+}
+
+func (r *csvLineReader) Line() int {
+	return r.line
+}
+
+// tableSource decodes the records of a table with headers, like a CSV file or a sheet, by their column names.
+type tableSource struct {
+	reader  tableReader
+	decoder *csvutil.Decoder
+}
+
+// newTableSource reads the headers of the table and checks that it has all expected headers, in any order.
+func newTableSource(reader tableReader, config options) (*tableSource, error) {
+	headers, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read headers: %w", err)
+	}
+
+	mappedHeaders, err := mapHeaders(headers, config.headerAliases)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder, err := csvutil.NewDecoder(reader, mappedHeaders...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create decoder: %w", err)
+	}
+	return &tableSource{reader: reader, decoder: decoder}, nil
+}
+
+func newCSVSource(file *os.File, config options) (Source, error) {
+	return newTableSource(&csvLineReader{Reader: newCSVReader(file, config)}, config)
+}
+
+func (s *tableSource) Header() []string {
+	return s.decoder.Header()
+}
+
+func (s *tableSource) Next() (Row, error) {
+	var request models.CreateBankRequest
+	err := s.decoder.Decode(&request)
+	if errors.Is(err, io.EOF) {
+		return Row{}, io.EOF
+	}
+	if errors.Is(err, errUnreadableTable) {
+		return Row{}, err
+	}
+	return Row{Line: s.reader.Line(), Record: append([]string(nil), s.decoder.Record()...), Request: request, Err: err}, nil
+}
+
+// jsonRecord is a bank data record of JSON and NDJSON files, using the field names of the API.
+// Fields are listed in the order of the expected CSV headers.
+type jsonRecord struct {
+	ISO2Code    string `json:"countryISO2"`
+	SWIFTCode   string `json:"swiftCode"`
+	CodeType    string `json:"codeType"`
+	BankName    string `json:"bankName"`
+	Address     string `json:"address"`
+	TownName    string `json:"townName"`
+	CountryName string `json:"countryName"`
+	TimeZone    string `json:"timeZone"`
+}
+
+// newJSONRecord converts the request to the record of an exported file.
+func newJSONRecord(request models.CreateBankRequest) jsonRecord {
+	return jsonRecord{
+		ISO2Code:    request.ISO2Code,
+		SWIFTCode:   request.SWIFTCode,
+		CodeType:    request.CodeType,
+		BankName:    request.BankName,
+		Address:     request.Address,
+		TownName:    request.TownName,
+		CountryName: request.CountryName,
+		TimeZone:    request.TimeZone,
+	}
+}
+
+// record returns the fields of the record in the order of the expected CSV headers.
+func (r jsonRecord) record() []string {
+	return []string{r.ISO2Code, r.SWIFTCode, r.CodeType, r.BankName, r.Address, r.TownName, r.CountryName, r.TimeZone}
+}
+
+// decodeJSONRecord decodes the JSON object into a row. Objects which cannot be decoded are returned
+// with the raw object as their only field.
+func decodeJSONRecord(line int, data []byte) Row {
+	var record jsonRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return Row{Line: line, Record: []string{string(data)}, Err: err}
+	}
+	return Row{
+		Line:   line,
+		Record: record.record(),
+		Request: models.CreateBankRequest{
+			Address:     record.Address,
+			BankName:    record.BankName,
+			ISO2Code:    record.ISO2Code,
+			CountryName: record.CountryName,
+			SWIFTCode:   record.SWIFTCode,
+			CodeType:    record.CodeType,
+			TownName:    record.TownName,
+			TimeZone:    record.TimeZone,
+		},
+	}
+}
+
+// ndjsonSource reads a JSON object from every non-empty line of the file.
+type ndjsonSource struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// maxNDJSONLineSize limits the length of a single NDJSON line.
+const maxNDJSONLineSize = 1024 * 1024
+
+func newNDJSONSource(file *os.File, config options) (Source, error) {
+	scanner := bufio.NewScanner(newContentReader(file, config))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLineSize)
+	return &ndjsonSource{scanner: scanner}, nil
+}
+
+func (s *ndjsonSource) Header() []string {
+	return correctHeaders
+}
+
+func (s *ndjsonSource) Next() (Row, error) {
+	for s.scanner.Scan() {
+		s.line++
+		line := bytes.TrimSpace(s.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		return decodeJSONRecord(s.line, line), nil
+	}
+	if err := s.scanner.Err(); err != nil {
+		return Row{}, fmt.Errorf("failed to read NDJSON line %d: %w", s.line+1, err)
+	}
+	return Row{}, io.EOF
+}
+
+// jsonSource reads the objects of a JSON array one by one.
+type jsonSource struct {
+	decoder *json.Decoder
+	index   int
+}
+
+func newJSONSource(file *os.File, config options) (Source, error) {
+	decoder := json.NewDecoder(newContentReader(file, config))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JSON array: %w", err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("JSON file must contain an array of bank records")
+	}
+	return &jsonSource{decoder: decoder}, nil
+}
+
+func (s *jsonSource) Header() []string {
+	return correctHeaders
+}
+
+func (s *jsonSource) Next() (Row, error) {
+	if !s.decoder.More() {
+		if _, err := s.decoder.Token(); err != nil {
+			return Row{}, fmt.Errorf("failed to read end of JSON array: %w", err)
+		}
+		return Row{}, io.EOF
+	}
+	s.index++
+	var data json.RawMessage
+	if err := s.decoder.Decode(&data); err != nil {
+		return Row{}, fmt.Errorf("failed to read JSON record %d: %w", s.index, err)
+	}
+	return decodeJSONRecord(s.index, data), nil
+}
@@ -0,0 +1,259 @@
+package parser
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// xlsxWorkbook is the list of sheets of xl/workbook.xml.
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// xlsxRelationships maps the relationship IDs of xl/_rels/workbook.xml.rels to the files of the package.
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is a plain or rich text of a shared string or an inline string cell.
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	var text strings.Builder
+	text.WriteString(t.Text)
+	for _, run := range t.Runs {
+		text.WriteString(run.Text)
+	}
+	return text.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxCell struct {
+	Ref    string   `xml:"r,attr"`
+	Type   string   `xml:"t,attr"`
+	Value  string   `xml:"v"`
+	Inline xlsxText `xml:"is"`
+}
+
+type xlsxRow struct {
+	Number int        `xml:"r,attr"`
+	Cells  []xlsxCell `xml:"c"`
+}
+
+// readXLSXPart decodes the XML file of the package into the value.
+func readXLSXPart(archive *zip.Reader, name string, value interface{}) error {
+	file, err := archive.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return xml.NewDecoder(file).Decode(value)
+}
+
+// xlsxSheetPath returns the path of the sheet with the name, or of the first sheet if the name is empty.
+func xlsxSheetPath(archive *zip.Reader, sheetName string) (string, error) {
+	var workbook xlsxWorkbook
+	if err := readXLSXPart(archive, "xl/workbook.xml", &workbook); err != nil {
+		return "", fmt.Errorf("failed to read workbook: %w", err)
+	}
+	var relationships xlsxRelationships
+	if err := readXLSXPart(archive, "xl/_rels/workbook.xml.rels", &relationships); err != nil {
+		return "", fmt.Errorf("failed to read workbook relationships: %w", err)
+	}
+
+	for _, sheet := range workbook.Sheets {
+		if sheetName != "" && !strings.EqualFold(sheet.Name, sheetName) {
+			continue
+		}
+		for _, relationship := range relationships.Relationships {
+			if relationship.ID != sheet.ID {
+				continue
+			}
+			// targets are relative to the xl directory, unless they are absolute
+			if strings.HasPrefix(relationship.Target, "/") {
+				return strings.TrimPrefix(relationship.Target, "/"), nil
+			}
+			return path.Join("xl", relationship.Target), nil
+		}
+		return "", fmt.Errorf("sheet %s has no file", sheet.Name)
+	}
+	if sheetName != "" {
+		return "", fmt.Errorf("sheet %s not found", sheetName)
+	}
+	return "", fmt.Errorf("workbook has no sheets")
+}
+
+// xlsxMaxColumns is the number of columns of a sheet, the last column is XFD.
+const xlsxMaxColumns = 16384
+
+// xlsxColumn returns the zero based column index of the cell reference, e.g. 27 for AB12.
+// It returns an error wrapping errUnreadableTable if the column is beyond the last column of a sheet.
+func xlsxColumn(ref string) (int, bool, error) {
+	column := 0
+	letters := 0
+	for _, r := range strings.ToUpper(ref) {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		column = column*26 + int(r-'A'+1)
+		if column > xlsxMaxColumns {
+			return 0, false, fmt.Errorf("%w: cell %s is beyond the last column XFD", errUnreadableTable, ref)
+		}
+		letters++
+	}
+	return column - 1, letters > 0, nil
+}
+
+// sheetReader streams the rows of a sheet as records of cell texts, skipping empty rows.
+type sheetReader struct {
+	file          io.ReadCloser
+	decoder       *xml.Decoder
+	sharedStrings []string
+	line          int
+	// width is the number of columns of the first row, shorter rows are padded to it
+	width int
+}
+
+func newXLSXSource(file *os.File, config options) (Source, error) {
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+	archive, err := zip.NewReader(file, fileInfo.Size())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open XLSX file: %w", err)
+	}
+
+	var sharedStrings xlsxSharedStrings
+	if err := readXLSXPart(archive, "xl/sharedStrings.xml", &sharedStrings); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read shared strings: %w", err)
+	}
+	sheetPath, err := xlsxSheetPath(archive, config.sheet)
+	if err != nil {
+		return nil, err
+	}
+	sheet, err := archive.Open(sheetPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sheet: %w", err)
+	}
+
+	reader := &sheetReader{file: sheet, decoder: xml.NewDecoder(sheet)}
+	for _, item := range sharedStrings.Items {
+		reader.sharedStrings = append(reader.sharedStrings, item.String())
+	}
+	source, err := newTableSource(reader, config)
+	if err != nil {
+		_ = sheet.Close()
+		return nil, err
+	}
+	return source, nil
+}
+
+func (r *sheetReader) Line() int {
+	return r.line
+}
+
+func (r *sheetReader) cellText(cell xlsxCell) (string, error) {
+	switch cell.Type {
+	case "s":
+		index, err := strconv.Atoi(cell.Value)
+		if err != nil || index < 0 || index >= len(r.sharedStrings) {
+			return "", fmt.Errorf("%w: cell %s refers to unknown shared string %q", errUnreadableTable, cell.Ref, cell.Value)
+		}
+		return r.sharedStrings[index], nil
+	case "inlineStr":
+		return cell.Inline.String(), nil
+	default:
+		return cell.Value, nil
+	}
+}
+
+// Read returns the texts of the cells of the next non-empty row, and io.EOF after the last row.
+// The sheet cannot be read after an error, so its file is closed.
+func (r *sheetReader) Read() ([]string, error) {
+	record, err := r.readRow()
+	if err != nil {
+		_ = r.file.Close()
+	}
+	return record, err
+}
+
+// readRow returns the texts of the cells of the next non-empty row, and io.EOF after the last row.
+func (r *sheetReader) readRow() ([]string, error) {
+	for {
+		token, err := r.decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to read sheet: %w", errUnreadableTable, err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+
+		var row xlsxRow
+		if err := r.decoder.DecodeElement(&row, &start); err != nil {
+			return nil, fmt.Errorf("%w: failed to read sheet row: %w", errUnreadableTable, err)
+		}
+		if row.Number > 0 {
+			r.line = row.Number
+		} else {
+			r.line++
+		}
+
+		record := make([]string, r.width)
+		empty := true
+		for i, cell := range row.Cells {
+			column, ok, err := xlsxColumn(cell.Ref)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				column = i
+			}
+			text, err := r.cellText(cell)
+			if err != nil {
+				return nil, err
+			}
+			for len(record) <= column {
+				record = append(record, "")
+			}
+			record[column] = text
+			empty = empty && strings.TrimSpace(text) == ""
+		}
+		if empty {
+			continue
+		}
+		if r.width == 0 {
+			r.width = len(record)
+		}
+		// formatted but empty cells after the last column are not part of the record
+		for len(record) > r.width && record[len(record)-1] == "" {
+			record = record[:len(record)-1]
+		}
+		return record, nil
+	}
+}
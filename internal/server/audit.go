@@ -0,0 +1,84 @@
+package server
+
+import (
+	"SWIFT-Remitly/internal/database"
+	"SWIFT-Remitly/internal/models"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// newIPExtractor returns the extractor of the client IP of requests recorded in the audit log, from the trusted
+// proxies, e.g. from an environment variable, in the format cidr,... If there are no trusted proxies, the IP is the one
+// of the connection, otherwise the X-Forwarded-For header of requests from the proxies is used.
+// Clients cannot choose their IP, so the proxies of loopback and private networks are not trusted unless listed.
+func newIPExtractor(value string) (echo.IPExtractor, error) {
+	if value == "" {
+		return echo.ExtractIPDirect(), nil
+	}
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, proxy := range strings.Split(value, ",") {
+		_, ipRange, err := net.ParseCIDR(strings.TrimSpace(proxy))
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q, expected a CIDR like 10.0.0.0/8", proxy)
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
+
+// newAuditEntry returns the audit entry of the request, identifying the actor, the client and the request.
+func newAuditEntry(c echo.Context, action models.AuditAction, swiftCode string) models.AuditEntry {
+	return models.AuditEntry{
+		Actor:     requestActor(c),
+		RemoteIP:  c.RealIP(),
+		RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+		Action:    action,
+		SWIFTCode: swiftCode,
+	}
+}
+
+// audited runs the mutation of the request in a transaction together with recording its audit entry,
+// see database.Service.Audited. The SWIFT code is the one of the path, empty for requests without it.
+// It returns the error of the mutation or of the audit log, which is mapped to status 500.
+func (s *Server) audited(c echo.Context, action models.AuditAction, swiftCode string,
+	mutation func(db database.Service) (database.AuditResult, error)) error {
+	return s.db.Audited(newAuditEntry(c, action, swiftCode), mutation)
+}
+
+// auditImport records the audit entry of the upload of an import, which stores the bank data in the background,
+// with the status of the error of starting it, or 202 if it started.
+// It returns an error wrapping database.ErrAuditFailed if the entry cannot be recorded.
+func (s *Server) auditImport(c echo.Context, startErr error) error {
+	entry := newAuditEntry(c, models.AuditImport, "")
+	entry.Status = http.StatusAccepted
+	if startErr != nil {
+		entry.Status = models.MapErrorToStatusCode(startErr).Status
+	}
+	if err := s.db.AddAuditEntry(entry); err != nil {
+		return fmt.Errorf("%w: %w", database.ErrAuditFailed, err)
+	}
+	return nil
+}
+
+// getAuditEntriesHandler returns the audit entries, newest first. The optional swiftCode, actor, from and to
+// query parameters filter the entries, limit caps their number.
+func (s *Server) getAuditEntriesHandler(c echo.Context) error {
+	query, err := models.ParseAuditQuery(c.QueryParam("swiftCode"), c.QueryParam("actor"),
+		c.QueryParam("from"), c.QueryParam("to"), c.QueryParam("limit"))
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	entries, err := s.db.GetAuditEntries(query)
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	return c.JSON(http.StatusOK, &entries)
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"SWIFT-Remitly/internal/models"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// actorKey is the key of the authenticated client in the context of a request, set by authenticate.
+	actorKey = "actor"
+	// anonymousActor is the actor of requests without an API key.
+	anonymousActor = "anonymous"
+)
+
+// parseAPIKeys converts the API keys, e.g. from an environment variable, in the format actor:key,... to keys by actor.
+// An empty value means no API keys.
+func parseAPIKeys(value string) (map[string]string, error) {
+	keys := map[string]string{}
+	if value == "" {
+		return keys, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		actor, key, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		actor, key = strings.TrimSpace(actor), strings.TrimSpace(key)
+		if !ok || actor == "" || key == "" {
+			return nil, fmt.Errorf("invalid API key %q, expected actor:key", pair)
+		}
+		if _, ok := keys[actor]; ok {
+			return nil, fmt.Errorf("actor %s has more than one API key", actor)
+		}
+		keys[actor] = key
+	}
+	return keys, nil
+}
+
+// authenticate sets the actor of requests with the API key of an actor in the bearer Authorization header.
+// Requests without the header are anonymous, requests with an unknown key are rejected with status 401.
+func (s *Server) authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		authorization := c.Request().Header.Get(echo.HeaderAuthorization)
+		if authorization == "" {
+			return next(c)
+		}
+		key, ok := strings.CutPrefix(authorization, "Bearer ")
+		if ok {
+			for actor, apiKey := range s.apiKeys {
+				if subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) == 1 {
+					c.Set(actorKey, actor)
+					return next(c)
+				}
+			}
+		}
+		errResponse := models.Response{Success: false, Status: http.StatusUnauthorized, Message: "Invalid API key"}
+		return c.JSON(errResponse.Status, errResponse)
+	}
+}
+
+// requireActor rejects anonymous requests with status 401, for endpoints which need the API key of an actor.
+func (s *Server) requireActor(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if requestActor(c) == anonymousActor {
+			errResponse := models.Response{Success: false, Status: http.StatusUnauthorized, Message: "API key required"}
+			return c.JSON(errResponse.Status, errResponse)
+		}
+		return next(c)
+	}
+}
+
+// requestActor returns the actor of the request set by authenticate, or anonymousActor if there is none.
+func requestActor(c echo.Context) string {
+	if actor, ok := c.Get(actorKey).(string); ok && actor != "" {
+		return actor
+	}
+	return anonymousActor
+}
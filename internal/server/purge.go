@@ -0,0 +1,32 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// purgeInterval is the time between purges of deleted banks.
+const purgeInterval = time.Hour
+
+// runPurgeJob permanently deletes the banks deleted longer than the retention period ago,
+// at start and then every purgeInterval, until the context is done.
+func (s *Server) runPurgeJob(ctx context.Context, retention time.Duration) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := s.db.PurgeDeletedBanks(time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("Purging deleted banks failed after %d banks: %v", purged, err)
+		} else if purged > 0 {
+			log.Printf("Purged %d banks deleted more than %v ago", purged, retention)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
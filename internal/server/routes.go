@@ -1,8 +1,15 @@
 package server
 
 import (
+	"SWIFT-Remitly/internal/database"
 	"SWIFT-Remitly/internal/models"
+	"SWIFT-Remitly/internal/parser"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -10,8 +17,10 @@ import (
 
 func (s *Server) RegisterRoutes() http.Handler {
 	e := echo.New()
+	e.IPExtractor = s.ipExtractor
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
+	e.Use(middleware.RequestID())
 
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins:     []string{"https://*", "http://*"},
@@ -20,6 +29,14 @@ func (s *Server) RegisterRoutes() http.Handler {
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
+	// after CORS, so browsers can read rejections of unknown API keys
+	e.Use(s.authenticate)
+
+	e.GET("/v1/swift-codes/export", s.exportBanksHandler)
+
+	e.GET("/v1/swift-codes/search", s.searchBanksHandler)
+
+	e.GET("/v1/swift-codes/deleted", s.getDeletedBanksHandler)
 
 	e.GET("/v1/swift-codes/:swift-code", s.getBankBySWIFTCodeHandler)
 
@@ -27,29 +44,76 @@ func (s *Server) RegisterRoutes() http.Handler {
 
 	e.POST("/v1/swift-codes", s.addBankDataHandler)
 
+	e.POST("/v1/swift-codes/lookup", s.lookupBanksHandler)
+
+	e.POST("/v1/swift-codes/batch", s.addBanksBatchHandler)
+
+	e.PUT("/v1/swift-codes/:swift-code", s.replaceBankDataHandler)
+
+	e.PATCH("/v1/swift-codes/:swift-code", s.updateBankDataHandler)
+
 	e.DELETE("/v1/swift-codes/:swift-code", s.deleteBankDataHandler)
 
+	e.POST("/v1/swift-codes/:swift-code/restore", s.restoreBankDataHandler)
+
+	e.GET("/v1/swift-codes/:swift-code/history", s.getBankHistoryHandler)
+
+	e.GET("/v1/institutions/:bic8", s.getInstitutionHandler)
+
+	e.GET("/v1/countries", s.getCountriesHandler)
+
+	e.POST("/v1/imports", s.createImportHandler)
+
+	e.GET("/v1/imports/:id", s.getImportHandler)
+
+	e.GET("/v1/audit", s.getAuditEntriesHandler, s.requireActor)
+
 	return e
 }
 
+// getBankBySWIFTCodeHandler returns the bank with the SWIFT code, the view query parameter selects its fields.
+// The asOf query parameter returns the bank data the SWIFT code resolved to at the time, without branches.
 func (s *Server) getBankBySWIFTCodeHandler(c echo.Context) error {
 	swiftCode := c.Param("swift-code")
 	if err := models.ValidateSWIFTCode(swiftCode); err != nil {
 		errResponse := models.MapErrorToStatusCode(err)
 		return c.JSON(errResponse.Status, errResponse)
 	}
+	view, err := models.ParseBankView(c.QueryParam("view"))
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+	asOf, err := models.ParseAsOf(c.QueryParam("asOf"))
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
 
-	bankData, err := s.db.GetBankBySwiftCode(swiftCode)
+	normalizedSWIFTCode := models.NormalizeSWIFTCode(swiftCode)
+	var bankData models.Bank
+	if asOf.IsZero() {
+		bankData, err = s.db.GetBankBySwiftCode(normalizedSWIFTCode)
+	} else {
+		bankData, err = s.db.GetBankAsOf(normalizedSWIFTCode, asOf)
+	}
 
 	if err != nil {
 		errResponse := models.MapErrorToStatusCode(err)
 		return c.JSON(errResponse.Status, errResponse)
 	}
+	if normalizedSWIFTCode != swiftCode {
+		bankData.OriginalSWIFTCode = swiftCode
+	}
+	bankData.View = view
 
 	return c.JSON(http.StatusOK, &bankData)
 
 }
 
+// getBanksByISO2CodeHandler returns a page of the banks of the country. The limit, cursor and sort
+// query parameters select the page, the response links to the next page with the same parameters.
+// The view query parameter selects the fields of the banks.
 func (s *Server) getBanksByISO2CodeHandler(c echo.Context) error {
 	iso2Code := c.Param("countryISO2code")
 	if err := models.ValidateISO2Code(iso2Code); err != nil {
@@ -57,15 +121,159 @@ func (s *Server) getBanksByISO2CodeHandler(c echo.Context) error {
 		return c.JSON(errResponse.Status, errResponse)
 	}
 
-	bankData, err := s.db.GetBanksByISO2Code(iso2Code)
+	page, err := models.ParsePageRequest(c.QueryParam("limit"), c.QueryParam("cursor"), c.QueryParam("sort"))
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+	view, err := models.ParseBankView(c.QueryParam("view"))
 	if err != nil {
 		errResponse := models.MapErrorToStatusCode(err)
 		return c.JSON(errResponse.Status, errResponse)
 	}
 
+	bankData, err := s.db.GetBanksByISO2Code(iso2Code, page)
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+	if bankData.NextCursor != "" {
+		next := *c.Request().URL
+		query := next.Query()
+		query.Set("cursor", bankData.NextCursor)
+		next.RawQuery = query.Encode()
+		bankData.Next = next.RequestURI()
+	}
+	bankData.SetView(view)
+
 	return c.JSON(http.StatusOK, &bankData)
 }
 
+// exportContentTypes are the content types of the export formats.
+var exportContentTypes = map[parser.Format]string{
+	parser.FormatCSV:    "text/csv; charset=utf-8",
+	parser.FormatNDJSON: "application/x-ndjson",
+	parser.FormatJSON:   echo.MIMEApplicationJSON,
+}
+
+// exportBanksHandler streams the bank data of all countries, or of the country of the country query parameter,
+// as a file in the csv, ndjson or json format of the format query parameter, which can be imported again.
+func (s *Server) exportBanksHandler(c echo.Context) error {
+	format, err := parser.ParseExportFormat(c.QueryParam("format"))
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(&models.ErrRequestInvalid{Message: "Request invalid", Details: []string{err.Error()}})
+		return c.JSON(errResponse.Status, errResponse)
+	}
+	iso2Code := c.QueryParam("country")
+	fileName := "swift-codes." + string(format)
+	if iso2Code != "" {
+		if err := models.ValidateISO2Code(iso2Code); err != nil {
+			errResponse := models.MapErrorToStatusCode(err)
+			return c.JSON(errResponse.Status, errResponse)
+		}
+		fileName = "swift-codes-" + iso2Code + "." + string(format)
+	}
+
+	// exports of the whole directory take longer than the write timeout of the server
+	if err := http.NewResponseController(c.Response()).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("failed to disable write deadline of export: %v", err)
+	}
+	c.Response().Header().Set(echo.HeaderContentType, exportContentTypes[format])
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", fileName))
+
+	if err := parser.Export(s.db, c.Response(), format, iso2Code); err != nil {
+		if c.Response().Committed {
+			// the status was already sent, the client gets a truncated file
+			log.Printf("Export of %s failed: %v", fileName, err)
+			return nil
+		}
+		c.Response().Header().Del(echo.HeaderContentDisposition)
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+	return nil
+}
+
+// searchBanksHandler returns the banks whose name, address or town matches the q query parameter, best matches first.
+// The optional country, town and headquarterOnly query parameters filter the banks, limit caps the number of results
+// and view selects the fields of the banks.
+func (s *Server) searchBanksHandler(c echo.Context) error {
+	query, err := models.ParseSearchQuery(c.QueryParam("q"), c.QueryParam("country"), c.QueryParam("town"),
+		c.QueryParam("headquarterOnly"), c.QueryParam("limit"))
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+	view, err := models.ParseBankView(c.QueryParam("view"))
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	results, err := s.db.SearchBanks(query)
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	response := models.SearchResponse{Query: query.Text, Results: results}
+	response.SetView(view)
+	return c.JSON(http.StatusOK, &response)
+}
+
+// maxLookupSWIFTCodes is the largest number of SWIFT codes of a single lookup request.
+const maxLookupSWIFTCodes = 10000
+
+// lookupBanksHandler returns the bank or the error of every SWIFT code of the request body.
+// Banks are retrieved with set-based queries instead of one query per SWIFT code.
+// The view query parameter selects the fields of the banks, asOf looks up the bank data at the time.
+func (s *Server) lookupBanksHandler(c echo.Context) error {
+	view, err := models.ParseBankView(c.QueryParam("view"))
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+	asOf, err := models.ParseAsOf(c.QueryParam("asOf"))
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	var req models.LookupRequest
+	if err := c.Bind(&req); err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+	if len(req.SWIFTCodes) == 0 || len(req.SWIFTCodes) > maxLookupSWIFTCodes {
+		errResponse := models.MapErrorToStatusCode(&models.ErrRequestInvalid{
+			Message: "Request invalid",
+			Details: []string{fmt.Sprintf("swiftCodes must list between 1 and %d SWIFT codes", maxLookupSWIFTCodes)},
+		})
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	swiftCodes := make([]string, 0, len(req.SWIFTCodes))
+	for _, swiftCode := range req.SWIFTCodes {
+		if models.ValidateSWIFTCode(swiftCode) == nil {
+			swiftCodes = append(swiftCodes, models.NormalizeSWIFTCode(swiftCode))
+		}
+	}
+	var banks map[string]models.Bank
+	if asOf.IsZero() {
+		banks, err = s.db.GetBanksBySwiftCodes(swiftCodes)
+	} else {
+		banks, err = s.db.GetBanksAsOf(swiftCodes, asOf)
+	}
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	response := models.NewLookupResponse(req.SWIFTCodes, banks)
+	response.SetView(view)
+	return c.JSON(http.StatusOK, &response)
+}
+
 func (s *Server) addBankDataHandler(c echo.Context) error {
 	var req models.CreateBankRequest
 	if err := c.Bind(&req); err != nil {
@@ -73,13 +281,148 @@ func (s *Server) addBankDataHandler(c echo.Context) error {
 		return c.JSON(errResponse.Status, errResponse)
 	}
 
-	err := s.db.AddBankFromRequest(req)
+	err := s.audited(c, models.AuditCreate, "", func(db database.Service) (database.AuditResult, error) {
+		if err := db.AddBankFromRequest(req); err != nil {
+			return database.AuditResult{}, err
+		}
+		return database.AuditResult{Status: http.StatusCreated, SWIFTCodes: []string{req.SWIFTCode}}, nil
+	})
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	okResponse := models.Response{
+		Success:           true,
+		Status:            http.StatusCreated,
+		Message:           "Bank data added successfully",
+		SWIFTCode:         req.SWIFTCode,
+		OriginalSWIFTCode: req.OriginalSWIFTCode,
+	}
+	return c.JSON(okResponse.Status, okResponse)
+}
+
+// maxBatchBanks is the largest number of banks of a single batch request.
+const maxBatchBanks = 1000
+
+// addBanksBatchHandler adds the banks of the request body in the order of the request. In atomic mode
+// all banks are added in a single transaction, otherwise every bank is added on its own and the response
+// has status 207 with the status of every bank.
+func (s *Server) addBanksBatchHandler(c echo.Context) error {
+	var req models.BatchRequest
+	if err := c.Bind(&req); err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+	if len(req.Banks) == 0 || len(req.Banks) > maxBatchBanks {
+		errResponse := models.MapErrorToStatusCode(&models.ErrRequestInvalid{
+			Message: "Request invalid",
+			Details: []string{fmt.Sprintf("banks must list between 1 and %d banks", maxBatchBanks)},
+		})
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	requests := make([]models.CreateBankRequest, len(req.Banks))
+	errs := make([]error, len(req.Banks))
+	var validRequests []models.CreateBankRequest
+	var validIndexes []int
+	for i, bank := range req.Banks {
+		if err := json.Unmarshal(bank, &requests[i]); err != nil {
+			var errRequestInvalid *models.ErrRequestInvalid
+			if !errors.As(err, &errRequestInvalid) {
+				err = &models.ErrRequestInvalid{Message: "Request invalid", Details: []string{err.Error()}}
+			}
+			errs[i] = err
+			continue
+		}
+		validRequests = append(validRequests, requests[i])
+		validIndexes = append(validIndexes, i)
+	}
+
+	// banks which were not created are recorded by a single audit entry with the status of the batch
+	var response models.BatchResponse
+	err := s.audited(c, models.AuditCreate, "", func(db database.Service) (database.AuditResult, error) {
+		var created []string
+		// atomic batches with an invalid bank are not stored at all
+		if len(validRequests) > 0 && (!req.Atomic || len(validRequests) == len(req.Banks)) {
+			mode := database.BulkBestEffort
+			if req.Atomic {
+				mode = database.BulkAtomic
+			}
+			results, err := db.AddBanksFromRequests(validRequests, mode)
+			if err != nil && !errors.Is(err, database.ErrBulkAborted) {
+				return database.AuditResult{}, err
+			}
+			for i, result := range results {
+				errs[validIndexes[i]] = result.Err
+				if result.Err == nil && err == nil {
+					created = append(created, validRequests[i].SWIFTCode)
+				}
+			}
+		}
+		response = models.NewBatchResponse(req.Atomic, requests, errs)
+		return database.AuditResult{Status: response.Status, SWIFTCodes: created}, nil
+	})
 	if err != nil {
 		errResponse := models.MapErrorToStatusCode(err)
 		return c.JSON(errResponse.Status, errResponse)
 	}
 
-	okResponse := models.Response{Success: true, Status: http.StatusCreated, Message: "Bank data added successfully"}
+	return c.JSON(response.Status, &response)
+}
+
+func (s *Server) replaceBankDataHandler(c echo.Context) error {
+	var req models.CreateBankRequest
+	if err := c.Bind(&req); err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	return s.updateBankData(c, models.AuditReplace, models.NewUpdateBankRequest(req))
+}
+
+func (s *Server) updateBankDataHandler(c echo.Context) error {
+	var req models.UpdateBankRequest
+	if err := c.Bind(&req); err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	return s.updateBankData(c, models.AuditUpdate, req)
+}
+
+// updateBankData updates the bank identified by the SWIFT code from the path, shared by PUT and PATCH handlers
+// which record the update in the audit log as the given action.
+func (s *Server) updateBankData(c echo.Context, action models.AuditAction, req models.UpdateBankRequest) error {
+	swiftCode := c.Param("swift-code")
+	if err := models.ValidateSWIFTCode(swiftCode); err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	normalizedSWIFTCode := models.NormalizeSWIFTCode(swiftCode)
+	okResponse := models.Response{
+		Success:   true,
+		Status:    http.StatusOK,
+		Message:   "Bank data updated successfully",
+		SWIFTCode: normalizedSWIFTCode,
+	}
+	if req.SWIFTCode != nil {
+		okResponse.SWIFTCode = models.NormalizeSWIFTCode(*req.SWIFTCode)
+	}
+	err := s.audited(c, action, normalizedSWIFTCode, func(db database.Service) (database.AuditResult, error) {
+		if err := db.UpdateBank(normalizedSWIFTCode, req); err != nil {
+			return database.AuditResult{}, err
+		}
+		return database.AuditResult{Status: okResponse.Status, SWIFTCodes: []string{okResponse.SWIFTCode}}, nil
+	})
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+	if normalizedSWIFTCode != swiftCode {
+		okResponse.OriginalSWIFTCode = swiftCode
+	}
 	return c.JSON(okResponse.Status, okResponse)
 }
 
@@ -90,12 +433,223 @@ func (s *Server) deleteBankDataHandler(c echo.Context) error {
 		return c.JSON(errResponse.Status, errResponse)
 	}
 
-	err := s.db.DeleteBankBySwiftCode(swiftCode)
+	normalizedSWIFTCode := models.NormalizeSWIFTCode(swiftCode)
+	err := s.audited(c, models.AuditDelete, normalizedSWIFTCode, func(db database.Service) (database.AuditResult, error) {
+		if err := db.DeleteBankBySwiftCode(normalizedSWIFTCode); err != nil {
+			return database.AuditResult{}, err
+		}
+		return database.AuditResult{Status: http.StatusOK}, nil
+	})
 	if err != nil {
 		errResponse := models.MapErrorToStatusCode(err)
 		return c.JSON(errResponse.Status, errResponse)
 	}
 
-	okResponse := models.Response{Success: true, Status: http.StatusOK, Message: "Bank data deleted successfully"}
+	okResponse := models.Response{
+		Success:   true,
+		Status:    http.StatusOK,
+		Message:   "Bank data deleted successfully",
+		SWIFTCode: normalizedSWIFTCode,
+	}
+	if normalizedSWIFTCode != swiftCode {
+		okResponse.OriginalSWIFTCode = swiftCode
+	}
 	return c.JSON(okResponse.Status, okResponse)
 }
+
+// restoreBankDataHandler restores the most recently deleted bank with the SWIFT code from the path,
+// unless it was purged or another bank with the SWIFT code was added after the deletion.
+func (s *Server) restoreBankDataHandler(c echo.Context) error {
+	swiftCode := c.Param("swift-code")
+	if err := models.ValidateSWIFTCode(swiftCode); err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	normalizedSWIFTCode := models.NormalizeSWIFTCode(swiftCode)
+	err := s.audited(c, models.AuditRestore, normalizedSWIFTCode, func(db database.Service) (database.AuditResult, error) {
+		if err := db.RestoreBankBySwiftCode(normalizedSWIFTCode); err != nil {
+			return database.AuditResult{}, err
+		}
+		return database.AuditResult{Status: http.StatusOK, SWIFTCodes: []string{normalizedSWIFTCode}}, nil
+	})
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	okResponse := models.Response{
+		Success:   true,
+		Status:    http.StatusOK,
+		Message:   "Bank data restored successfully",
+		SWIFTCode: normalizedSWIFTCode,
+	}
+	if normalizedSWIFTCode != swiftCode {
+		okResponse.OriginalSWIFTCode = swiftCode
+	}
+	return c.JSON(okResponse.Status, okResponse)
+}
+
+// getBankHistoryHandler returns the changes of the banks stored under the SWIFT code from the path, oldest first.
+func (s *Server) getBankHistoryHandler(c echo.Context) error {
+	swiftCode := c.Param("swift-code")
+	if err := models.ValidateSWIFTCode(swiftCode); err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	history, err := s.db.GetBankHistory(models.NormalizeSWIFTCode(swiftCode))
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	return c.JSON(http.StatusOK, &history)
+}
+
+// getDeletedBanksHandler returns the deleted banks which are not purged yet, most recently deleted first.
+// The view query parameter selects the fields of the banks.
+func (s *Server) getDeletedBanksHandler(c echo.Context) error {
+	view, err := models.ParseBankView(c.QueryParam("view"))
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	banks, err := s.db.GetDeletedBanks()
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+	for i := range banks {
+		banks[i].View = view
+	}
+
+	return c.JSON(http.StatusOK, &banks)
+}
+
+// getInstitutionHandler returns the headquarter and the branches sharing the institution code (BIC8) from the path.
+func (s *Server) getInstitutionHandler(c echo.Context) error {
+	institutionCode := c.Param("bic8")
+	if err := models.ValidateInstitutionCode(institutionCode); err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+	view, err := models.ParseBankView(c.QueryParam("view"))
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	institution, err := s.db.GetInstitution(institutionCode)
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	institution.SetView(view)
+
+	return c.JSON(http.StatusOK, &institution)
+}
+
+func (s *Server) getCountriesHandler(c echo.Context) error {
+	countries, err := s.db.GetCountries()
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	return c.JSON(http.StatusOK, &countries)
+}
+
+// parseFormatOptions converts the delimiter, encoding, format and sheet of import files to the parser options.
+// Empty values add no option, so the defaults of the import jobs apply.
+func parseFormatOptions(delimiter string, encoding string, format string, sheet string) ([]parser.Option, error) {
+	var opts []parser.Option
+	if delimiter != "" {
+		value, err := parser.ParseDelimiter(delimiter)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, parser.WithDelimiter(value))
+	}
+	if encoding != "" {
+		value, err := parser.ParseEncoding(encoding)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, parser.WithEncoding(value))
+	}
+	if format != "" {
+		value, err := parser.ParseFormat(format)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, parser.WithFormat(value))
+	}
+	if sheet != "" {
+		opts = append(opts, parser.WithSheet(sheet))
+	}
+	return opts, nil
+}
+
+// createImportHandler starts a background import of the CSV, NDJSON, JSON or XLSX file uploaded in the file form field.
+// The optional mode form field enables the bulk import in the best-effort or atomic mode,
+// the optional format, sheet, delimiter and encoding form fields describe the format of the file,
+// overriding the CSV_FORMAT, CSV_SHEET, CSV_DELIMITER and CSV_ENCODING settings of the server.
+func (s *Server) createImportHandler(c echo.Context) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(&models.ErrRequestInvalid{
+			Message: "Request invalid",
+			Details: []string{"File must be uploaded in the file form field"},
+		})
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	var opts []parser.Option
+	if mode := c.FormValue("mode"); mode != "" {
+		bulkMode, err := database.ParseBulkMode(mode)
+		if err != nil {
+			errResponse := models.MapErrorToStatusCode(&models.ErrRequestInvalid{Message: "Request invalid", Details: []string{err.Error()}})
+			return c.JSON(errResponse.Status, errResponse)
+		}
+		opts = append(opts, parser.WithBulkImport(bulkMode))
+	}
+	formatOpts, err := parseFormatOptions(c.FormValue("delimiter"), c.FormValue("encoding"),
+		c.FormValue("format"), c.FormValue("sheet"))
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(&models.ErrRequestInvalid{Message: "Request invalid", Details: []string{err.Error()}})
+		return c.JSON(errResponse.Status, errResponse)
+	}
+	opts = append(opts, formatOpts...)
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+	defer file.Close()
+
+	job, err := s.imports.Start(fileHeader.Filename, file, opts...)
+	if auditErr := s.auditImport(c, err); auditErr != nil {
+		errResponse := models.MapErrorToStatusCode(auditErr)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	return c.JSON(http.StatusAccepted, &job)
+}
+
+func (s *Server) getImportHandler(c echo.Context) error {
+	job, err := s.imports.Get(c.Param("id"))
+	if err != nil {
+		errResponse := models.MapErrorToStatusCode(err)
+		return c.JSON(errResponse.Status, errResponse)
+	}
+
+	return c.JSON(http.StatusOK, &job)
+}
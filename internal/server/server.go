@@ -1,28 +1,66 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"strconv"
 	"time"
 
 	_ "github.com/joho/godotenv/autoload"
+	"github.com/labstack/echo/v4"
 
 	"SWIFT-Remitly/internal/database"
+	"SWIFT-Remitly/internal/parser"
 )
 
 type Server struct {
 	port int
 
 	db database.Service
+
+	imports *parser.ImportJobs
+
+	// apiKeys are the API keys of the actors recorded in the audit log, by actor
+	apiKeys map[string]string
+	// ipExtractor extracts the client IP of requests recorded in the audit log
+	ipExtractor echo.IPExtractor
 }
 
 func NewServer() *http.Server {
 	port, _ := strconv.Atoi(os.Getenv("PORT"))
+	headerAliases, err := parser.ParseHeaderAliases(os.Getenv("CSV_HEADER_ALIASES"))
+	if err != nil {
+		log.Fatalf("Error parsing header aliases: %v", err)
+	}
+	formatOpts, err := parseFormatOptions(os.Getenv("CSV_DELIMITER"), os.Getenv("CSV_ENCODING"),
+		os.Getenv("CSV_FORMAT"), os.Getenv("CSV_SHEET"))
+	if err != nil {
+		log.Fatalf("Error parsing import file format: %v", err)
+	}
+	retention, err := database.ParseRetention(os.Getenv("DELETED_BANKS_RETENTION"))
+	if err != nil {
+		log.Fatalf("Error parsing deleted banks retention: %v", err)
+	}
+
+	apiKeys, err := parseAPIKeys(os.Getenv("API_KEYS"))
+	if err != nil {
+		log.Fatalf("Error parsing API keys: %v", err)
+	}
+	ipExtractor, err := newIPExtractor(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		log.Fatalf("Error parsing trusted proxies: %v", err)
+	}
+
+	db := database.New(nil)
 	NewServer := &Server{
-		port: port,
-		db:   database.New(nil),
+		port:        port,
+		db:          db,
+		imports:     parser.NewImportJobs(db, append(formatOpts, parser.WithHeaderAliases(headerAliases))...),
+		apiKeys:     apiKeys,
+		ipExtractor: ipExtractor,
 	}
 
 	// Declare Server config
@@ -34,5 +72,9 @@ func NewServer() *http.Server {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	go NewServer.runPurgeJob(purgeCtx, retention)
+	server.RegisterOnShutdown(stopPurge)
+
 	return server
 }
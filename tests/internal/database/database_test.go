@@ -4,9 +4,14 @@ import (
 	"SWIFT-Remitly/internal/database"
 	"SWIFT-Remitly/internal/models"
 	"context"
+	"errors"
+	"gorm.io/gorm"
 	"log"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestMain(m *testing.M) {
@@ -56,7 +61,7 @@ func runGetBanksByISO2CodeTests(t *testing.T, testCases []getBanksByISO2CodeTest
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			Setup()
-			banks, err := srv.GetBanksByISO2Code(tc.iso2Code)
+			banks, err := srv.GetBanksByISO2Code(tc.iso2Code, models.PageRequest{})
 			if tc.expected {
 				if err != nil {
 					t.Fatalf("Name: %v, expected nil, got %v", tc.name, err)
@@ -92,6 +97,78 @@ func TestGetBanksByISO2Code(t *testing.T) {
 	runGetBanksByISO2CodeTests(t, testCases)
 }
 
+func TestGetBanksByISO2CodePages(t *testing.T) {
+	db := GetDb()
+	srv := database.New(db)
+
+	testCases := []struct {
+		name  string
+		limit int
+		sort  models.BankSort
+		pages [][]string
+	}{
+		{"By SWIFT code", 3, models.SortBySWIFTCode, [][]string{{"ALBPPLP1BMW", "BREXPLPWWAL", "BREXPLPWWRO"}, {"BREXPLPWXXX"}}},
+		{"By bank name", 2, models.SortByBankName, [][]string{{"ALBPPLP1BMW", "BREXPLPWWAL"}, {"BREXPLPWWRO", "BREXPLPWXXX"}}},
+		{"By town", 2, models.SortByTown, [][]string{{"BREXPLPWWAL", "BREXPLPWWRO"}, {"BREXPLPWXXX", "ALBPPLP1BMW"}}},
+		{"Single page", 4, models.SortBySWIFTCode, [][]string{{"ALBPPLP1BMW", "BREXPLPWWAL", "BREXPLPWWRO", "BREXPLPWXXX"}}},
+		{"Without limit", 0, models.SortBySWIFTCode, [][]string{{"ALBPPLP1BMW", "BREXPLPWWAL", "BREXPLPWWRO", "BREXPLPWXXX"}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			Setup()
+			page := models.PageRequest{Limit: tc.limit, Sort: tc.sort}
+			for i, expected := range tc.pages {
+				country, err := srv.GetBanksByISO2Code("PL", page)
+				if err != nil {
+					t.Fatalf("Name: %v, expected nil, got %v", tc.name, err)
+				}
+				var swiftCodes []string
+				for _, bank := range country.Banks {
+					swiftCodes = append(swiftCodes, bank.SWIFTCode)
+				}
+				if strings.Join(swiftCodes, ",") != strings.Join(expected, ",") {
+					t.Fatalf("Name: %v, expected page %d %v, got %v", tc.name, i+1, expected, swiftCodes)
+				}
+
+				lastPage := i == len(tc.pages)-1
+				if lastPage != (country.NextCursor == "") {
+					t.Fatalf("Name: %v, expected next cursor on page %d only if it is not the last page, got %q", tc.name, i+1, country.NextCursor)
+				}
+				if lastPage {
+					break
+				}
+				page, err = models.ParsePageRequest(strconv.Itoa(tc.limit), country.NextCursor, string(tc.sort))
+				if err != nil {
+					t.Fatalf("Name: %v, expected nil, got %v", tc.name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestGetCountries(t *testing.T) {
+	db := GetDb()
+	srv := database.New(db)
+	Setup()
+
+	countries, err := srv.GetCountries()
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+
+	// every country of the registry and NT stored only in the database
+	if len(countries) != len(models.ListCountries())+1 {
+		t.Fatalf("Expected %v countries, got %v", len(models.ListCountries())+1, len(countries))
+	}
+
+	expectedBankCounts := map[string]int64{"PL": 4, "US": 1, "NT": 0, "DE": 0}
+	for _, country := range countries {
+		if expected, ok := expectedBankCounts[country.ISO2Code]; ok && country.BankCount != expected {
+			t.Fatalf("Expected %v banks in %v, got %v", expected, country.ISO2Code, country.BankCount)
+		}
+	}
+}
+
 type getBankBySWIFTCodeTestCase struct {
 	name                   string
 	swiftCode              string
@@ -142,6 +219,162 @@ func TestGetBankBySWIFTCode(t *testing.T) {
 	runGetBankBySWIFTCodeTests(t, testCases)
 }
 
+func TestGetBanksBySwiftCodes(t *testing.T) {
+	db := GetDb()
+	srv := database.New(db)
+	Setup()
+
+	banks, err := srv.GetBanksBySwiftCodes([]string{"BREXPLPWXXX", "AAISALTRXXX", "ALBPPLP1BMW", "AAAAPLPWXXX"})
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	if len(banks) != 3 {
+		t.Fatalf("Expected 3 stored banks, got %v", banks)
+	}
+	bank, ok := banks["ALBPPLP1BMW"]
+	if !ok || bank.Country.ISO2Code != "PL" || bank.Address.Town.Town == "" || bank.Name.Name == "" || bank.Branches != nil {
+		t.Fatalf("Expected ALBPPLP1BMW with lookup table records, got %+v", bank)
+	}
+	// headquarters are retrieved with their branches, like by GetBankBySwiftCode
+	if branches := banks["BREXPLPWXXX"].Branches; len(branches) != 2 || branches[0].Name.Name == "" {
+		t.Fatalf("Expected BREXPLPWXXX with 2 branches, got %+v", branches)
+	}
+	if branches := banks["AAISALTRXXX"].Branches; branches == nil || len(branches) != 0 {
+		t.Fatalf("Expected AAISALTRXXX with empty branches, got %+v", branches)
+	}
+}
+
+func TestGetInstitution(t *testing.T) {
+	db := GetDb()
+	srv := database.New(db)
+
+	testCases := []struct {
+		name        string
+		code        string
+		expected    bool
+		headquarter string
+		branches    []string
+	}{
+		{"Headquarter with branches", "BREXPLPW", true, "BREXPLPWXXX", []string{"BREXPLPWWAL", "BREXPLPWWRO"}},
+		{"Headquarter without branches", "AAISALTR", true, "AAISALTRXXX", nil},
+		{"Orphan branch", "ALBPPLP1", true, "", []string{"ALBPPLP1BMW"}},
+		{"Not stored institution", "AAAAPLPW", false, "", nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			Setup()
+			institution, err := srv.GetInstitution(tc.code)
+			if !tc.expected {
+				if !errors.Is(err, gorm.ErrRecordNotFound) {
+					t.Fatalf("Name: %v, expected %v, got %v", tc.name, gorm.ErrRecordNotFound, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Name: %v, expected nil, got %v", tc.name, err)
+			}
+			if (institution.Headquarter == nil) != (tc.headquarter == "") ||
+				(institution.Headquarter != nil && institution.Headquarter.SWIFTCode != tc.headquarter) {
+				t.Fatalf("Name: %v, expected headquarter %q, got %+v", tc.name, tc.headquarter, institution.Headquarter)
+			}
+			var branches []string
+			for _, branch := range institution.Branches {
+				branches = append(branches, branch.SWIFTCode)
+			}
+			if strings.Join(branches, ",") != strings.Join(tc.branches, ",") {
+				t.Fatalf("Name: %v, expected branches %v, got %v", tc.name, tc.branches, branches)
+			}
+		})
+	}
+}
+
+func TestExportBanks(t *testing.T) {
+	db := GetDb()
+	srv := database.New(db)
+
+	testCases := []struct {
+		name       string
+		iso2Code   string
+		expected   bool
+		swiftCodes []string
+	}{
+		{"All countries", "", true, []string{"AAISALTRXXX", "ALBPPLP1BMW", "BREXPLPWWAL", "BREXPLPWWRO", "BREXPLPWXXX"}},
+		{"Country PL", "PL", true, []string{"ALBPPLP1BMW", "BREXPLPWWAL", "BREXPLPWWRO", "BREXPLPWXXX"}},
+		{"Country without banks", "NT", true, nil},
+		{"Not stored country", "XX", false, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			Setup()
+			var swiftCodes []string
+			err := srv.ExportBanks(tc.iso2Code, func(banks []models.Bank) error {
+				for _, bank := range banks {
+					if bank.Name.Name == "" || bank.Address.Town.Town == "" || bank.CodeType.CodeType == "" {
+						t.Fatalf("Name: %v, expected %v with lookup table records, got %+v", tc.name, bank.SWIFTCode, bank)
+					}
+					swiftCodes = append(swiftCodes, bank.SWIFTCode)
+				}
+				return nil
+			})
+			if !tc.expected {
+				if err == nil {
+					t.Fatalf("Name: %v, expected error, got nil", tc.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Name: %v, expected nil, got %v", tc.name, err)
+			}
+			if strings.Join(swiftCodes, ",") != strings.Join(tc.swiftCodes, ",") {
+				t.Fatalf("Name: %v, expected %v, got %v", tc.name, tc.swiftCodes, swiftCodes)
+			}
+		})
+	}
+}
+
+func TestSearchBanks(t *testing.T) {
+	db := GetDb()
+	srv := database.New(db)
+
+	testCases := []struct {
+		name       string
+		query      models.SearchQuery
+		swiftCodes []string
+	}{
+		{"By bank name", models.SearchQuery{Text: "bankname", Limit: 10}, []string{"AAISALTRXXX", "ALBPPLP1BMW"}},
+		{"By similar bank name", models.SearchQuery{Text: "UsedInMultipleBank", Limit: 10}, []string{"BREXPLPWWAL", "BREXPLPWWRO", "BREXPLPWXXX"}},
+		// similar towns and addresses are matched too, with a lower rank
+		{"By town", models.SearchQuery{Text: "Town3", Limit: 1}, []string{"ALBPPLP1BMW"}},
+		{"By address", models.SearchQuery{Text: "Address4", Limit: 1}, []string{"ALBPPLP1BMW"}},
+		{"In country", models.SearchQuery{Text: "bankname", ISO2Code: "PL", Limit: 10}, []string{"ALBPPLP1BMW"}},
+		{"In town", models.SearchQuery{Text: "bankname", Town: "TOWN3", Limit: 10}, []string{"ALBPPLP1BMW"}},
+		{"Headquarters only", models.SearchQuery{Text: "UsedInMultipleBanks", HeadquarterOnly: true, Limit: 10}, []string{"BREXPLPWXXX"}},
+		{"Limited", models.SearchQuery{Text: "bankname", Limit: 1}, []string{"AAISALTRXXX"}},
+		{"Wildcards are not patterns", models.SearchQuery{Text: "%_", Limit: 10}, nil},
+		{"No match", models.SearchQuery{Text: "nothing like this", Limit: 10}, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			Setup()
+			results, err := srv.SearchBanks(tc.query)
+			if err != nil {
+				t.Fatalf("Name: %v, expected nil, got %v", tc.name, err)
+			}
+			var swiftCodes []string
+			for i, result := range results {
+				if i > 0 && result.Rank > results[i-1].Rank {
+					t.Fatalf("Name: %v, expected results ordered by rank, got %+v", tc.name, results)
+				}
+				swiftCodes = append(swiftCodes, result.Bank.SWIFTCode)
+			}
+			// banks with the same rank are ordered by SWIFT code
+			if strings.Join(swiftCodes, ",") != strings.Join(tc.swiftCodes, ",") {
+				t.Fatalf("Name: %v, expected %v, got %v", tc.name, tc.swiftCodes, swiftCodes)
+			}
+		})
+	}
+}
+
 type addBankFromRequestTestCase struct {
 	name     string
 	request  models.CreateBankRequest
@@ -186,14 +419,19 @@ func runAddBankFromRequestTests(t *testing.T, testCases []addBankFromRequestTest
 					t.Fatalf("Expected nil, got %v", err)
 				}
 
-				var timeZone models.TimeZone
-				if err := db.Where("time_zone = ?", tc.request.TimeZone).First(&timeZone).Error; err != nil {
-					t.Fatalf("Expected nil, got %v", err)
-				}
-
 				var bank models.Bank
-				bankQuery := db.Where("swift_code = ? AND code_type_id = ? AND name_id = ? AND address_id = ? AND country_id = ? AND time_zone_id = ?",
-					tc.request.SWIFTCode, codeType.ID, name.ID, address.ID, country.ID, timeZone.ID)
+				bankQuery := db.Where("swift_code = ? AND code_type_id = ? AND name_id = ? AND address_id = ? AND country_id = ?",
+					tc.request.SWIFTCode, codeType.ID, name.ID, address.ID, country.ID)
+
+				if tc.request.TimeZone == "" {
+					bankQuery = bankQuery.Where("time_zone_id IS NULL")
+				} else {
+					var timeZone models.TimeZone
+					if err := db.Where("time_zone = ?", tc.request.TimeZone).First(&timeZone).Error; err != nil {
+						t.Fatalf("Expected nil, got %v", err)
+					}
+					bankQuery = bankQuery.Where("time_zone_id = ?", timeZone.ID)
+				}
 
 				if tc.hqLinked {
 					bankQuery = bankQuery.Where("headquarter_id IS NOT NULL")
@@ -243,7 +481,7 @@ func TestAddBankFromRequest(t *testing.T) {
 				BankName:      "Test Bank",
 				ISO2Code:      "PL",
 				CountryName:   "POLAND",
-				SWIFTCode:     "TESTTESTXXX",
+				SWIFTCode:     "TESTPLPWXXX",
 				CodeType:      "BIC",
 				TownName:      "Test Town",
 				TimeZone:      "CET",
@@ -275,7 +513,7 @@ func TestAddBankFromRequest(t *testing.T) {
 				BankName:      "Test Bank",
 				ISO2Code:      "PL",
 				CountryName:   "POLAND",
-				SWIFTCode:     "TESTTESTNOT",
+				SWIFTCode:     "TESTPLPWNOT",
 				CodeType:      "BIC",
 				TownName:      "Test Town",
 				TimeZone:      "CET",
@@ -291,7 +529,7 @@ func TestAddBankFromRequest(t *testing.T) {
 				BankName:      "Test Bank",
 				ISO2Code:      "XXW",
 				CountryName:   "INVALID",
-				SWIFTCode:     "TESTTESTXXX",
+				SWIFTCode:     "TESTPLPWXXX",
 				CodeType:      "BIC",
 				TownName:      "Test Town",
 				TimeZone:      "CET",
@@ -316,6 +554,54 @@ func TestAddBankFromRequest(t *testing.T) {
 			false,
 			false,
 		},
+		{
+			"With time zone of another country with the same offsets",
+			models.CreateBankRequest{
+				Address:       "Test Address",
+				BankName:      "Test Bank",
+				ISO2Code:      "PL",
+				CountryName:   "POLAND",
+				SWIFTCode:     "TESTPLPWXXX",
+				CodeType:      "BIC",
+				TownName:      "Test Town",
+				TimeZone:      "Europe/Berlin",
+				IsHeadquarter: true,
+			},
+			true,
+			false,
+		},
+		{
+			"With unknown time zone",
+			models.CreateBankRequest{
+				Address:       "Test Address",
+				BankName:      "Test Bank",
+				ISO2Code:      "PL",
+				CountryName:   "POLAND",
+				SWIFTCode:     "TESTPLPWXXX",
+				CodeType:      "BIC",
+				TownName:      "Test Town",
+				TimeZone:      "Europe/Atlantis",
+				IsHeadquarter: true,
+			},
+			false,
+			false,
+		},
+		{
+			"With time zone not belonging to the country",
+			models.CreateBankRequest{
+				Address:       "Test Address",
+				BankName:      "Test Bank",
+				ISO2Code:      "PL",
+				CountryName:   "POLAND",
+				SWIFTCode:     "TESTPLPWXXX",
+				CodeType:      "BIC",
+				TownName:      "Test Town",
+				TimeZone:      "America/New_York",
+				IsHeadquarter: true,
+			},
+			false,
+			false,
+		},
 		{
 			"Missing not required field",
 			models.CreateBankRequest{
@@ -323,7 +609,7 @@ func TestAddBankFromRequest(t *testing.T) {
 				BankName:      "Test Bank",
 				ISO2Code:      "PL",
 				CountryName:   "POLAND",
-				SWIFTCode:     "TESTTESTXXX",
+				SWIFTCode:     "TESTPLPWXXX",
 				IsHeadquarter: true,
 			},
 			true,
@@ -333,6 +619,462 @@ func TestAddBankFromRequest(t *testing.T) {
 	runAddBankFromRequestTests(t, testCases)
 }
 
+func stringPtr(value string) *string {
+	return &value
+}
+
+type updateBankTestCase struct {
+	name      string
+	swiftCode string
+	request   models.UpdateBankRequest
+	expected  bool
+	check     func(t *testing.T, db *gorm.DB)
+}
+
+func runUpdateBankTests(t *testing.T, testCases []updateBankTestCase) {
+	db := GetDb()
+	srv := database.New(db)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			Setup()
+			err := srv.UpdateBank(tc.swiftCode, tc.request)
+			if tc.expected {
+				if err != nil {
+					t.Fatalf("Name: %v, expected nil, got %v", tc.name, err)
+				}
+				tc.check(t, db)
+			} else {
+				if err == nil {
+					t.Fatalf("Name: %v, expected error, got nil", tc.name)
+				}
+			}
+		})
+	}
+}
+
+func countBranches(t *testing.T, db *gorm.DB, headquarterCode string) int64 {
+	var count int64
+	if err := db.Model(&models.Bank{}).
+		Where("headquarter_id = (SELECT id FROM banks WHERE swift_code = ?)", headquarterCode).
+		Count(&count).Error; err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	return count
+}
+
+func TestUpdateBank(t *testing.T) {
+	testCases := []updateBankTestCase{
+		{
+			"Update address of branch bank",
+			"BREXPLPWWRO", // uses Address2, which is not used by other banks
+			models.UpdateBankRequest{Address: stringPtr("New Address")},
+			true,
+			func(t *testing.T, db *gorm.DB) {
+				var bank models.Bank
+				if err := db.Preload("Address").Where("swift_code = ?", "BREXPLPWWRO").First(&bank).Error; err != nil {
+					t.Fatalf("Expected nil, got %v", err)
+				}
+				if bank.Address.Address != "New Address" {
+					t.Fatalf("Expected New Address, got %v", bank.Address.Address)
+				}
+				if bank.HeadquarterID == nil {
+					t.Fatalf("Expected branch to stay linked to headquarter")
+				}
+				if err := db.Where("address = ?", "Address2").First(&models.BankAddress{}).Error; err == nil {
+					t.Fatalf("Expected orphaned Address2 to be deleted")
+				}
+				if err := db.Where("town = ?", "Town1").First(&models.BankTown{}).Error; err != nil {
+					t.Fatalf("Expected Town1 used by other addresses to stay, got %v", err)
+				}
+			},
+		},
+		{
+			"Update bank name of headquarter",
+			"BREXPLPWXXX",
+			models.UpdateBankRequest{BankName: stringPtr("New Name")},
+			true,
+			func(t *testing.T, db *gorm.DB) {
+				if err := db.Where("name = ?", "UsedInMultipleBanks").First(&models.BankName{}).Error; err != nil {
+					t.Fatalf("Expected name used by branches to stay, got %v", err)
+				}
+				if count := countBranches(t, db, "BREXPLPWXXX"); count != 2 {
+					t.Fatalf("Expected 2 branches, got %v", count)
+				}
+			},
+		},
+		{
+			"Change SWIFT code of headquarter",
+			"BREXPLPWXXX",
+			models.UpdateBankRequest{SWIFTCode: stringPtr("BREXPLPA")},
+			true,
+			func(t *testing.T, db *gorm.DB) {
+				var branches []models.Bank
+				if err := db.Where("swift_code LIKE ? AND headquarter_id IS NOT NULL", "BREXPLPW%").Find(&branches).Error; err != nil {
+					t.Fatalf("Expected nil, got %v", err)
+				}
+				if len(branches) != 0 {
+					t.Fatalf("Expected branches to be unlinked, got %v linked", len(branches))
+				}
+			},
+		},
+		{
+			"Change branch SWIFT code to other institution",
+			"ALBPPLP1BMW",
+			models.UpdateBankRequest{SWIFTCode: stringPtr("BREXPLPWBMW")},
+			true,
+			func(t *testing.T, db *gorm.DB) {
+				if count := countBranches(t, db, "BREXPLPWXXX"); count != 3 {
+					t.Fatalf("Expected 3 branches, got %v", count)
+				}
+			},
+		},
+		{
+			"Change SWIFT code to existing one",
+			"BREXPLPWWRO",
+			models.UpdateBankRequest{SWIFTCode: stringPtr("BREXPLPWWAL")},
+			false,
+			nil,
+		},
+		{
+			"Country not matching SWIFT code",
+			"BREXPLPWXXX",
+			models.UpdateBankRequest{ISO2Code: stringPtr("DE"), CountryName: stringPtr("GERMANY")},
+			false,
+			nil,
+		},
+		{
+			"Update town and time zone",
+			"BREXPLPWWRO",
+			models.UpdateBankRequest{TownName: stringPtr("WROCLAW"), TimeZone: stringPtr("Europe/Warsaw")},
+			true,
+			func(t *testing.T, db *gorm.DB) {
+				request := storedBankRequest(t, db, "BREXPLPWWRO")
+				if request.TownName != "WROCLAW" || request.TimeZone != "Europe/Warsaw" || request.CodeType != "CodeType1" {
+					t.Fatalf("Expected updated town and time zone with unchanged code type, got %+v", request)
+				}
+			},
+		},
+		{
+			"Time zone of another country",
+			"BREXPLPWWRO",
+			models.UpdateBankRequest{TimeZone: stringPtr("America/New_York")},
+			false,
+			nil,
+		},
+		{
+			"Not existing SWIFT code",
+			"TESTPLPWNOT",
+			models.UpdateBankRequest{Address: stringPtr("New Address")},
+			false,
+			nil,
+		},
+	}
+
+	runUpdateBankTests(t, testCases)
+}
+
+type upsertBankFromRequestTestCase struct {
+	name           string
+	swiftCode      string // stored bank used as the base of the request, empty for new banks
+	modify         func(request *models.CreateBankRequest)
+	expected       bool
+	expectedResult models.UpsertResult
+}
+
+func storedBankRequest(t *testing.T, db *gorm.DB, swiftCode string) models.CreateBankRequest {
+	var bank models.Bank
+	if err := db.
+		Preload("Address.Town").
+		Preload("CodeType").
+		Preload("Country").
+		Preload("Name").
+		Preload("TimeZone").
+		Where("swift_code = ?", swiftCode).
+		First(&bank).Error; err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	return bank.ToCreateBankRequest()
+}
+
+func runUpsertBankFromRequestTests(t *testing.T, testCases []upsertBankFromRequestTestCase) {
+	db := GetDb()
+	srv := database.New(db)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			Setup()
+			var request models.CreateBankRequest
+			if tc.swiftCode != "" {
+				request = storedBankRequest(t, db, tc.swiftCode)
+			}
+			tc.modify(&request)
+
+			result, err := srv.UpsertBankFromRequest(request)
+			if !tc.expected {
+				if err == nil {
+					t.Fatalf("Name: %v, expected error, got nil", tc.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Name: %v, expected nil, got %v", tc.name, err)
+			}
+			if result != tc.expectedResult {
+				t.Fatalf("Name: %v, expected result %v, got %v", tc.name, tc.expectedResult, result)
+			}
+
+			request.Normalize()
+			stored := storedBankRequest(t, db, request.SWIFTCode)
+			if !stored.SameBankData(request) {
+				t.Fatalf("Name: %v, expected stored bank %+v, got %+v", tc.name, request, stored)
+			}
+
+			var count int64
+			if err := db.Model(&models.Bank{}).Where("swift_code = ?", request.SWIFTCode).Count(&count).Error; err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+			if count != 1 {
+				t.Fatalf("Name: %v, expected 1 bank, got %v", tc.name, count)
+			}
+		})
+	}
+}
+
+func TestUpsertBankFromRequest(t *testing.T) {
+	testCases := []upsertBankFromRequestTestCase{
+		{
+			"New bank",
+			"",
+			func(request *models.CreateBankRequest) {
+				*request = models.CreateBankRequest{
+					Address:     "Test Address",
+					BankName:    "Test Bank",
+					ISO2Code:    "PL",
+					CountryName: "POLAND",
+					SWIFTCode:   "TESTPLPWXXX",
+					CodeType:    "BIC",
+					TownName:    "Test Town",
+					TimeZone:    "CET",
+				}
+			},
+			true,
+			models.UpsertInserted,
+		},
+		{
+			"Identical bank",
+			"BREXPLPWWRO",
+			func(request *models.CreateBankRequest) {},
+			true,
+			models.UpsertUnchanged,
+		},
+		{
+			"Identical bank with BIC8 SWIFT code",
+			"BREXPLPWXXX",
+			func(request *models.CreateBankRequest) { request.SWIFTCode = "BREXPLPW" },
+			true,
+			models.UpsertUnchanged,
+		},
+		{
+			"Changed bank name",
+			"BREXPLPWWRO",
+			func(request *models.CreateBankRequest) { request.BankName = "New Name" },
+			true,
+			models.UpsertUpdated,
+		},
+		{
+			"Changed address and town",
+			"ALBPPLP1BMW",
+			func(request *models.CreateBankRequest) {
+				request.Address = "New Address"
+				request.TownName = "New Town"
+			},
+			true,
+			models.UpsertUpdated,
+		},
+		{
+			"Country not matching SWIFT code",
+			"BREXPLPWWRO",
+			func(request *models.CreateBankRequest) {
+				request.ISO2Code = "DE"
+				request.CountryName = "GERMANY"
+			},
+			false,
+			0,
+		},
+	}
+
+	runUpsertBankFromRequestTests(t, testCases)
+}
+
+func newBankRequest(swiftCode string, bankName string) models.CreateBankRequest {
+	return models.CreateBankRequest{
+		Address:     "Bulk Address",
+		BankName:    bankName,
+		ISO2Code:    "PL",
+		CountryName: "POLAND",
+		SWIFTCode:   swiftCode,
+		CodeType:    "BIC11",
+		TownName:    "Bulk Town",
+		TimeZone:    "Europe/Warsaw",
+	}
+}
+
+func TestAddBanksFromRequests(t *testing.T) {
+	db := GetDb()
+	srv := database.New(db)
+
+	t.Run("Best effort", func(t *testing.T) {
+		Setup()
+		requests := []models.CreateBankRequest{
+			newBankRequest("BTCHPLPWXXX", "Batch Bank"),
+			newBankRequest("BTCHPLPWBRA", "Batch Bank"),
+			newBankRequest("BREXPLPWXXX", "Batch Bank"), // already stored
+			newBankRequest("BTCHDEFFXXX", "Batch Bank"), // SWIFT code country does not match
+		}
+
+		results, err := srv.AddBanksFromRequests(requests, database.BulkBestEffort)
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if results[0].Err != nil || results[1].Err != nil || !errors.Is(results[2].Err, gorm.ErrDuplicatedKey) || results[3].Err == nil {
+			t.Fatalf("Expected the last two requests to fail, got %+v", results)
+		}
+		if count := countBranches(t, db, "BTCHPLPWXXX"); count != 1 {
+			t.Fatalf("Expected branch to be linked to its headquarter, got %v branches", count)
+		}
+	})
+
+	t.Run("Atomic with failing request", func(t *testing.T) {
+		Setup()
+		var before int64
+		if err := db.Model(&models.Bank{}).Count(&before).Error; err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+
+		requests := []models.CreateBankRequest{
+			newBankRequest("BTCHPLPWXXX", "Batch Bank"),
+			newBankRequest("BREXPLPWXXX", "Batch Bank"),
+			newBankRequest("BTCHDEFFXXX", "Batch Bank"),
+		}
+		results, err := srv.AddBanksFromRequests(requests, database.BulkAtomic)
+		if !errors.Is(err, database.ErrBulkAborted) {
+			t.Fatalf("Expected %v, got %v", database.ErrBulkAborted, err)
+		}
+		if results[0].Err != nil || results[1].Err == nil || results[2].Err == nil {
+			t.Fatalf("Expected every failing request to be reported, got %+v", results)
+		}
+
+		var after int64
+		if err := db.Model(&models.Bank{}).Count(&after).Error; err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if before != after {
+			t.Fatalf("Expected no banks to be stored, got %v banks instead of %v", after, before)
+		}
+	})
+
+	t.Run("Atomic", func(t *testing.T) {
+		Setup()
+		requests := []models.CreateBankRequest{
+			newBankRequest("BTCHPLPWXXX", "Batch Bank"),
+			newBankRequest("BTCHPLPWBRA", "Batch Bank"),
+		}
+		if _, err := srv.AddBanksFromRequests(requests, database.BulkAtomic); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if count := countBranches(t, db, "BTCHPLPWXXX"); count != 1 {
+			t.Fatalf("Expected branch to be linked to its headquarter, got %v branches", count)
+		}
+	})
+}
+
+func TestBulkUpsertBanks(t *testing.T) {
+	db := GetDb()
+	srv := database.New(db)
+
+	t.Run("Best effort", func(t *testing.T) {
+		Setup()
+		updated := storedBankRequest(t, db, "ALBPPLP1BMW")
+		updated.BankName = "Bulk Updated Name"
+		updated.TimeZone = "Europe/Warsaw"
+		requests := []models.CreateBankRequest{
+			newBankRequest("BULKPLPWBRA", "Bulk Bank"), // branch before its headquarter
+			newBankRequest("BULKPLPW", "Bulk Bank"),
+			newBankRequest("BULKPLPWXXX", "Bulk Bank"),
+			updated,
+			newBankRequest("BULKDEFFXXX", "Bulk Bank"), // SWIFT code country does not match
+			newBankRequest("BULKPLPWBRA", "Bulk Bank Renamed"),
+		}
+		expected := []models.UpsertResult{
+			models.UpsertInserted, models.UpsertInserted, models.UpsertUnchanged, models.UpsertUpdated, 0, models.UpsertUpdated,
+		}
+
+		results, err := srv.BulkUpsertBanks(requests, database.BulkBestEffort)
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		for i, result := range results {
+			if (result.Err != nil) != (expected[i] == 0) || result.Result != expected[i] {
+				t.Fatalf("Request %d: expected result %v, got %+v", i, expected[i], result)
+			}
+		}
+
+		if count := countBranches(t, db, "BULKPLPWXXX"); count != 1 {
+			t.Fatalf("Expected branch parsed before headquarter to be linked, got %v branches", count)
+		}
+		if stored := storedBankRequest(t, db, "BULKPLPWBRA"); stored.BankName != "Bulk Bank Renamed" {
+			t.Fatalf("Expected the latest data of repeated bank, got %v", stored.BankName)
+		}
+		if stored := storedBankRequest(t, db, "ALBPPLP1BMW"); !stored.SameBankData(updated) {
+			t.Fatalf("Expected updated bank %+v, got %+v", updated, stored)
+		}
+		if err := db.Where("name = ?", "BankName3").First(&models.BankName{}).Error; err == nil {
+			t.Fatalf("Expected unused BankName3 to be deleted")
+		}
+	})
+
+	t.Run("Atomic with invalid request", func(t *testing.T) {
+		Setup()
+		var before int64
+		if err := db.Model(&models.Bank{}).Count(&before).Error; err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+
+		requests := []models.CreateBankRequest{
+			newBankRequest("BULKPLPWXXX", "Bulk Bank"),
+			newBankRequest("BULKDEFFXXX", "Bulk Bank"),
+		}
+		results, err := srv.BulkUpsertBanks(requests, database.BulkAtomic)
+		if !errors.Is(err, database.ErrBulkAborted) {
+			t.Fatalf("Expected %v, got %v", database.ErrBulkAborted, err)
+		}
+		if results[0].Err != nil || results[1].Err == nil {
+			t.Fatalf("Expected only the second request to fail, got %+v", results)
+		}
+
+		var after int64
+		if err := db.Model(&models.Bank{}).Count(&after).Error; err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if before != after {
+			t.Fatalf("Expected no banks to be stored, got %v banks instead of %v", after, before)
+		}
+	})
+
+	t.Run("Atomic", func(t *testing.T) {
+		Setup()
+		requests := []models.CreateBankRequest{
+			newBankRequest("BULKPLPWBRA", "Bulk Bank"),
+			newBankRequest("BULKPLPWXXX", "Bulk Bank"),
+		}
+		if _, err := srv.BulkUpsertBanks(requests, database.BulkAtomic); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if count := countBranches(t, db, "BULKPLPWXXX"); count != 1 {
+			t.Fatalf("Expected 1 branch, got %v", count)
+		}
+	})
+}
+
 type deleteWithBankCheck struct {
 	name         string
 	expectedType interface{}
@@ -363,6 +1105,9 @@ func runDeleteBankBySWIFTCodeTests(t *testing.T, testCases []deleteBankBySwiftCo
 				if err := db.Where("swift_code = ?", tc.swiftCode).First(&bank).Error; err == nil {
 					t.Fatalf("Name: %v, expected error, got nil", tc.name)
 				}
+				if err := db.Unscoped().Where("swift_code = ?", tc.swiftCode).First(&bank).Error; err != nil || !bank.DeletedAt.Valid {
+					t.Fatalf("Name: %v, expected deleted bank to be kept, got %+v, %v", tc.name, bank, err)
+				}
 
 				for _, check := range tc.deleteWith {
 					err := db.Where("id = ?", check.id).First(check.expectedType).Error
@@ -399,14 +1144,15 @@ func TestDeleteBankBySwiftCode(t *testing.T) {
 			true,
 		},
 		{
+			// lookup table records of deleted banks are kept until the banks are purged
 			"Delete HQ bank without branches",
 			"AAISALTRXXX", // bank AAISALTRXXX in mock_database_data.go
 			[]deleteWithBankCheck{
-				{"CodeType", &models.CodeType{}, 2, true},
-				{"NameBank", &models.BankName{}, 2, true},
+				{"CodeType", &models.CodeType{}, 2, false},
+				{"NameBank", &models.BankName{}, 2, false},
 				{"Address", &models.BankTown{}, 1, false},
-				{"Country", &models.BankCountry{}, 2, true},
-				{"TimeZone", &models.TimeZone{}, 2, true},
+				{"Country", &models.BankCountry{}, 2, false},
+				{"TimeZone", &models.TimeZone{}, 2, false},
 			},
 			true,
 		},
@@ -424,7 +1170,7 @@ func TestDeleteBankBySwiftCode(t *testing.T) {
 		},
 		{
 			"Not existing SWIFT code",
-			"TESTTESTNOT",
+			"TESTPLPWNOT",
 			[]deleteWithBankCheck{},
 			false,
 		},
@@ -432,3 +1178,493 @@ func TestDeleteBankBySwiftCode(t *testing.T) {
 
 	runDeleteBankBySWIFTCodeTests(t, testCases)
 }
+
+func TestDeleteHeadquarterUnlinksBranches(t *testing.T) {
+	db := GetDb()
+	srv := database.New(db)
+	Setup()
+
+	if err := srv.DeleteBankBySwiftCode("BREXPLPWXXX"); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	var linked int64
+	if err := db.Unscoped().Model(&models.Bank{}).Where("headquarter_id IS NOT NULL").Count(&linked).Error; err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	if linked != 0 {
+		t.Fatalf("Expected branches of deleted headquarter to be unlinked, got %v linked banks", linked)
+	}
+}
+
+func TestRestoreBankBySwiftCode(t *testing.T) {
+	db := GetDb()
+	srv := database.New(db)
+
+	t.Run("Restore headquarter", func(t *testing.T) {
+		Setup()
+		if err := srv.DeleteBankBySwiftCode("BREXPLPWXXX"); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if err := srv.RestoreBankBySwiftCode("BREXPLPWXXX"); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if count := countBranches(t, db, "BREXPLPWXXX"); count != 2 {
+			t.Fatalf("Expected 2 branches linked again, got %v", count)
+		}
+	})
+
+	t.Run("Restore branch", func(t *testing.T) {
+		Setup()
+		if err := srv.DeleteBankBySwiftCode("BREXPLPWWRO"); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if count := countBranches(t, db, "BREXPLPWXXX"); count != 1 {
+			t.Fatalf("Expected deleted branch to be hidden, got %v branches", count)
+		}
+		if err := srv.RestoreBankBySwiftCode("BREXPLPWWRO"); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if count := countBranches(t, db, "BREXPLPWXXX"); count != 2 {
+			t.Fatalf("Expected restored branch to be linked again, got %v branches", count)
+		}
+	})
+
+	t.Run("SWIFT code used by a new bank", func(t *testing.T) {
+		Setup()
+		if err := srv.DeleteBankBySwiftCode("BREXPLPWWRO"); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if err := srv.AddBankFromRequest(newBankRequest("BREXPLPWWRO", "New Bank")); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if err := srv.RestoreBankBySwiftCode("BREXPLPWWRO"); !errors.Is(err, gorm.ErrDuplicatedKey) {
+			t.Fatalf("Expected %v, got %v", gorm.ErrDuplicatedKey, err)
+		}
+	})
+
+	t.Run("Bank not deleted", func(t *testing.T) {
+		Setup()
+		if err := srv.RestoreBankBySwiftCode("BREXPLPWWRO"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("Expected %v, got %v", gorm.ErrRecordNotFound, err)
+		}
+	})
+}
+
+func TestGetDeletedBanks(t *testing.T) {
+	db := GetDb()
+	srv := database.New(db)
+	Setup()
+
+	for _, swiftCode := range []string{"BREXPLPWWRO", "AAISALTRXXX"} {
+		if err := srv.DeleteBankBySwiftCode(swiftCode); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+	}
+
+	banks, err := srv.GetDeletedBanks()
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	if len(banks) != 2 || banks[0].SWIFTCode != "AAISALTRXXX" || banks[1].SWIFTCode != "BREXPLPWWRO" {
+		t.Fatalf("Expected deleted banks, most recently deleted first, got %+v", banks)
+	}
+	if !banks[0].DeletedAt.Valid || banks[0].Name.Name != "BankName2" {
+		t.Fatalf("Expected deleted bank with deletion time and lookup table records, got %+v", banks[0])
+	}
+}
+
+func TestPurgeDeletedBanks(t *testing.T) {
+	db := GetDb()
+	srv := database.New(db)
+
+	t.Run("Banks deleted after the time are kept", func(t *testing.T) {
+		Setup()
+		if err := srv.DeleteBankBySwiftCode("AAISALTRXXX"); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		purged, err := srv.PurgeDeletedBanks(time.Now().Add(-time.Hour))
+		if err != nil || purged != 0 {
+			t.Fatalf("Expected no purged banks, got %v, %v", purged, err)
+		}
+	})
+
+	t.Run("Purge removes unused lookup table records", func(t *testing.T) {
+		Setup()
+		for _, swiftCode := range []string{"AAISALTRXXX", "BREXPLPWXXX"} {
+			if err := srv.DeleteBankBySwiftCode(swiftCode); err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+		}
+		purged, err := srv.PurgeDeletedBanks(time.Now().Add(time.Minute))
+		if err != nil || purged != 2 {
+			t.Fatalf("Expected 2 purged banks, got %v, %v", purged, err)
+		}
+
+		var bank models.Bank
+		if err := db.Unscoped().Where("swift_code = ?", "AAISALTRXXX").First(&bank).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("Expected purged bank to be removed, got %v", err)
+		}
+		// records of AAISALTRXXX are removed, records used by the branches of BREXPLPWXXX are kept
+		for _, check := range []deleteWithBankCheck{
+			{"CodeType", &models.CodeType{}, 2, true},
+			{"NameBank", &models.BankName{}, 2, true},
+			{"Country", &models.BankCountry{}, 2, true},
+			{"TimeZone", &models.TimeZone{}, 2, true},
+			{"CodeType", &models.CodeType{}, 1, false},
+			{"NameBank", &models.BankName{}, 1, false},
+			{"Country", &models.BankCountry{}, 1, false},
+		} {
+			err := db.Where("id = ?", check.id).First(check.expectedType).Error
+			if check.expected && err == nil {
+				t.Fatalf("Expected %v %v to be removed", check.name, check.id)
+			}
+			if !check.expected && err != nil {
+				t.Fatalf("Expected %v %v to be kept, got %v", check.name, check.id, err)
+			}
+		}
+	})
+
+	t.Run("Purge removes unused towns", func(t *testing.T) {
+		Setup()
+		if err := srv.DeleteBankBySwiftCode("ALBPPLP1BMW"); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		purged, err := srv.PurgeDeletedBanks(time.Now().Add(time.Minute))
+		if err != nil || purged != 1 {
+			t.Fatalf("Expected 1 purged bank, got %v, %v", purged, err)
+		}
+
+		// Address4 and Town3 were used only by ALBPPLP1BMW
+		for _, check := range []deleteWithBankCheck{
+			{"Address", &models.BankAddress{}, 5, true},
+			{"Town", &models.BankTown{}, 3, true},
+			{"Town", &models.BankTown{}, 1, false},
+		} {
+			err := db.Where("id = ?", check.id).First(check.expectedType).Error
+			if check.expected && err == nil {
+				t.Fatalf("Expected %v %v to be removed", check.name, check.id)
+			}
+			if !check.expected && err != nil {
+				t.Fatalf("Expected %v %v to be kept, got %v", check.name, check.id, err)
+			}
+		}
+	})
+}
+
+func TestParseRetention(t *testing.T) {
+	for period, expected := range map[string]time.Duration{"": database.DefaultRetention, "48h": 48 * time.Hour, "0s": 0, "-1h": 0, "30d": 0} {
+		retention, err := database.ParseRetention(period)
+		if expected == 0 {
+			if err == nil {
+				t.Fatalf("Period: %q, expected error, got %v", period, retention)
+			}
+			continue
+		}
+		if err != nil || retention != expected {
+			t.Fatalf("Period: %q, expected %v, got %v, %v", period, expected, retention, err)
+		}
+	}
+}
+
+// changeOperations returns the operations of the changes of the history.
+func changeOperations(history models.BankHistory) []models.ChangeOperation {
+	operations := make([]models.ChangeOperation, len(history.Changes))
+	for i, change := range history.Changes {
+		operations[i] = change.Operation
+	}
+	return operations
+}
+
+func TestGetBankHistory(t *testing.T) {
+	db := GetDb()
+	srv := database.New(db)
+
+	t.Run("Changes of a bank", func(t *testing.T) {
+		Setup()
+		if err := srv.AddBankFromRequest(newBankRequest("HSTRPLPWXXX", "History Bank")); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if err := srv.UpdateBank("HSTRPLPWXXX", models.UpdateBankRequest{BankName: stringPtr("Renamed Bank")}); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if err := srv.DeleteBankBySwiftCode("HSTRPLPWXXX"); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if err := srv.RestoreBankBySwiftCode("HSTRPLPWXXX"); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+
+		history, err := srv.GetBankHistory("HSTRPLPWXXX")
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		expected := []models.ChangeOperation{models.ChangeCreated, models.ChangeUpdated, models.ChangeDeleted, models.ChangeRestored}
+		if operations := changeOperations(history); !reflect.DeepEqual(operations, expected) {
+			t.Fatalf("Expected operations %v, got %v", expected, operations)
+		}
+		first := history.Changes[0]
+		if first.BankName != "History Bank" || first.TownName != "Bulk Town" || first.CountryName != "POLAND" || first.TimeZone != "Europe/Warsaw" {
+			t.Fatalf("Expected data of the created bank, got %+v", first)
+		}
+		if history.Changes[1].BankName != "Renamed Bank" || history.Changes[2].BankName != "Renamed Bank" {
+			t.Fatalf("Expected data of the updated bank, got %+v", history.Changes[1:])
+		}
+	})
+
+	t.Run("Changed SWIFT code", func(t *testing.T) {
+		Setup()
+		if err := srv.AddBankFromRequest(newBankRequest("HSTRPLPWXXX", "History Bank")); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if err := srv.UpdateBank("HSTRPLPWXXX", models.UpdateBankRequest{SWIFTCode: stringPtr("HSTRPLPWWAW")}); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+
+		oldHistory, err := srv.GetBankHistory("HSTRPLPWXXX")
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if operations := changeOperations(oldHistory); !reflect.DeepEqual(operations, []models.ChangeOperation{models.ChangeCreated, models.ChangeDeleted}) {
+			t.Fatalf("Expected old SWIFT code to be created and deleted, got %v", operations)
+		}
+		newHistory, err := srv.GetBankHistory("HSTRPLPWWAW")
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if operations := changeOperations(newHistory); !reflect.DeepEqual(operations, []models.ChangeOperation{models.ChangeCreated}) {
+			t.Fatalf("Expected new SWIFT code to be created, got %v", operations)
+		}
+	})
+
+	t.Run("Bulk upsert", func(t *testing.T) {
+		Setup()
+		if _, err := srv.BulkUpsertBanks([]models.CreateBankRequest{newBankRequest("HSTRPLPWXXX", "History Bank")}, database.BulkAtomic); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if _, err := srv.BulkUpsertBanks([]models.CreateBankRequest{newBankRequest("HSTRPLPWXXX", "Renamed Bank")}, database.BulkBestEffort); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+
+		history, err := srv.GetBankHistory("HSTRPLPWXXX")
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if operations := changeOperations(history); !reflect.DeepEqual(operations, []models.ChangeOperation{models.ChangeCreated, models.ChangeUpdated}) {
+			t.Fatalf("Expected bank to be created and updated, got %v", operations)
+		}
+	})
+
+	t.Run("Unknown SWIFT code", func(t *testing.T) {
+		Setup()
+		if _, err := srv.GetBankHistory("TESTPLPWNOT"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("Expected %v, got %v", gorm.ErrRecordNotFound, err)
+		}
+	})
+}
+
+func TestGetBanksAsOf(t *testing.T) {
+	db := GetDb()
+	srv := database.New(db)
+	Setup()
+
+	if err := srv.AddBankFromRequest(newBankRequest("HSTRPLPWXXX", "History Bank")); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	if err := srv.UpdateBank("HSTRPLPWXXX", models.UpdateBankRequest{BankName: stringPtr("Renamed Bank")}); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	if err := srv.DeleteBankBySwiftCode("HSTRPLPWXXX"); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	history, err := srv.GetBankHistory("HSTRPLPWXXX")
+	if err != nil || len(history.Changes) != 3 {
+		t.Fatalf("Expected 3 changes, got %+v, %v", history, err)
+	}
+	created, updated, deleted := history.Changes[0].ChangedAt, history.Changes[1].ChangedAt, history.Changes[2].ChangedAt
+
+	testCases := []struct {
+		name     string
+		asOf     time.Time
+		expected string
+	}{
+		{"Before creation", created.Add(-time.Microsecond), ""},
+		{"Created", created, "History Bank"},
+		{"Updated", updated, "Renamed Bank"},
+		{"Deleted", deleted, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			banks, err := srv.GetBanksAsOf([]string{"HSTRPLPWXXX", "TESTPLPWNOT"}, tc.asOf)
+			if err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+			bank, ok := banks["HSTRPLPWXXX"]
+			if tc.expected == "" {
+				if ok || len(banks) != 0 {
+					t.Fatalf("Expected no banks, got %+v", banks)
+				}
+				return
+			}
+			if !ok || len(banks) != 1 || bank.Name.Name != tc.expected || bank.Address.Town.Town != "Bulk Town" {
+				t.Fatalf("Expected bank %v, got %+v", tc.expected, banks)
+			}
+		})
+	}
+
+	t.Run("Single SWIFT code", func(t *testing.T) {
+		if _, err := srv.GetBankAsOf("HSTRPLPWXXX", deleted); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("Expected %v, got %v", gorm.ErrRecordNotFound, err)
+		}
+		bank, err := srv.GetBankAsOf("HSTRPLPWXXX", created)
+		if err != nil || bank.Name.Name != "History Bank" {
+			t.Fatalf("Expected created bank, got %+v, %v", bank, err)
+		}
+	})
+}
+
+func TestAuditEntries(t *testing.T) {
+	db := GetDb()
+	srv := database.New(db)
+	Setup()
+
+	entries := []models.AuditEntry{
+		{Actor: "admin", RemoteIP: "10.0.0.1", RequestID: "request-1", Action: models.AuditCreate, SWIFTCode: "BREXPLPWXXX", Status: 201, After: models.AuditPayload(`{"swiftCode":"BREXPLPWXXX"}`)},
+		{Actor: "anonymous", RemoteIP: "10.0.0.2", RequestID: "request-2", Action: models.AuditDelete, SWIFTCode: "BREXPLPWXXX", Status: 200, Before: models.AuditPayload(`{"swiftCode":"BREXPLPWXXX"}`)},
+		{Actor: "admin", RemoteIP: "10.0.0.1", RequestID: "request-3", Action: models.AuditImport, Status: 202},
+	}
+	for _, entry := range entries {
+		if err := srv.AddAuditEntry(entry); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		query    models.AuditQuery
+		expected []string
+	}{
+		{"All entries newest first", models.AuditQuery{Limit: 10}, []string{"request-3", "request-2", "request-1"}},
+		{"By SWIFT code", models.AuditQuery{SWIFTCode: "BREXPLPWXXX", Limit: 10}, []string{"request-2", "request-1"}},
+		{"By actor", models.AuditQuery{Actor: "admin", Limit: 10}, []string{"request-3", "request-1"}},
+		{"Limited", models.AuditQuery{Limit: 1}, []string{"request-3"}},
+		{"From the future", models.AuditQuery{From: time.Now().Add(time.Hour), Limit: 10}, []string{}},
+		{"To the past", models.AuditQuery{To: time.Now().Add(-time.Hour), Limit: 10}, []string{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			found, err := srv.GetAuditEntries(tc.query)
+			if err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+			requestIDs := make([]string, len(found))
+			for i, entry := range found {
+				requestIDs[i] = entry.RequestID
+			}
+			if !reflect.DeepEqual(requestIDs, tc.expected) {
+				t.Fatalf("Expected entries %v, got %v", tc.expected, requestIDs)
+			}
+		})
+	}
+
+	t.Run("Payloads", func(t *testing.T) {
+		found, err := srv.GetAuditEntries(models.AuditQuery{Actor: "anonymous", Limit: 10})
+		if err != nil || len(found) != 1 {
+			t.Fatalf("Expected 1 entry, got %+v, %v", found, err)
+		}
+		if string(found[0].Before) != `{"swiftCode": "BREXPLPWXXX"}` || found[0].After != nil {
+			t.Fatalf("Expected stored payloads, got %s and %s", found[0].Before, found[0].After)
+		}
+	})
+
+	t.Run("Append-only", func(t *testing.T) {
+		if err := db.Model(&models.AuditEntry{}).Where("request_id = ?", "request-1").Update("actor", "someone").Error; err == nil {
+			t.Fatalf("Expected error updating audit entry, got nil")
+		}
+		if err := db.Where("request_id = ?", "request-1").Delete(&models.AuditEntry{}).Error; err == nil {
+			t.Fatalf("Expected error deleting audit entry, got nil")
+		}
+	})
+}
+
+func TestAudited(t *testing.T) {
+	db := GetDb()
+	srv := database.New(db)
+
+	auditEntries := func(t *testing.T, actor string) []models.AuditEntry {
+		found, err := srv.GetAuditEntries(models.AuditQuery{Actor: actor, Limit: 10})
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		return found
+	}
+
+	t.Run("Update recorded with bank before and after", func(t *testing.T) {
+		Setup()
+		entry := models.AuditEntry{Actor: "updater", Action: models.AuditUpdate, SWIFTCode: "BREXPLPWWRO"}
+		name := "Audited Bank"
+		err := srv.Audited(entry, func(db database.Service) (database.AuditResult, error) {
+			if err := db.UpdateBank("BREXPLPWWRO", models.UpdateBankRequest{BankName: &name}); err != nil {
+				return database.AuditResult{}, err
+			}
+			return database.AuditResult{Status: 200, SWIFTCodes: []string{"BREXPLPWWRO"}}, nil
+		})
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		found := auditEntries(t, "updater")
+		if len(found) != 1 || found[0].Status != 200 || found[0].SWIFTCode != "BREXPLPWWRO" {
+			t.Fatalf("Expected 1 entry with status 200, got %+v", found)
+		}
+		if found[0].Before == nil || strings.Contains(string(found[0].Before), name) {
+			t.Fatalf("Expected bank before the update, got %s", found[0].Before)
+		}
+		if !strings.Contains(string(found[0].After), name) {
+			t.Fatalf("Expected bank after the update, got %s", found[0].After)
+		}
+	})
+
+	t.Run("Failed mutation rolled back and recorded with its status", func(t *testing.T) {
+		Setup()
+		entry := models.AuditEntry{Actor: "deleter", Action: models.AuditDelete, SWIFTCode: "BREXPLPWWRO"}
+		err := srv.Audited(entry, func(db database.Service) (database.AuditResult, error) {
+			if err := db.DeleteBankBySwiftCode("BREXPLPWWRO"); err != nil {
+				return database.AuditResult{}, err
+			}
+			return database.AuditResult{}, gorm.ErrRecordNotFound
+		})
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("Expected %v, got %v", gorm.ErrRecordNotFound, err)
+		}
+		if _, err := srv.GetBankBySwiftCode("BREXPLPWWRO"); err != nil {
+			t.Fatalf("Expected deletion rolled back, got %v", err)
+		}
+		found := auditEntries(t, "deleter")
+		if len(found) != 1 || found[0].Status != 404 || found[0].Before == nil || found[0].After != nil {
+			t.Fatalf("Expected 1 entry with status 404 and the bank before, got %+v", found)
+		}
+	})
+
+	t.Run("Entry for every created bank", func(t *testing.T) {
+		Setup()
+		entry := models.AuditEntry{Actor: "creator", Action: models.AuditCreate}
+		err := srv.Audited(entry, func(db database.Service) (database.AuditResult, error) {
+			requests := []models.CreateBankRequest{newBankRequest("AUDTPLPWXXX", "Audit Bank"), newBankRequest("AUDTPLPWWAW", "Audit Branch")}
+			if _, err := db.AddBanksFromRequests(requests, database.BulkAtomic); err != nil {
+				return database.AuditResult{}, err
+			}
+			return database.AuditResult{Status: 201, SWIFTCodes: []string{"AUDTPLPWXXX", "AUDTPLPWWAW"}}, nil
+		})
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		found := auditEntries(t, "creator")
+		if len(found) != 2 {
+			t.Fatalf("Expected 2 entries, got %+v", found)
+		}
+		for _, entry := range found {
+			if entry.Status != 201 || entry.Before != nil || !strings.Contains(string(entry.After), entry.SWIFTCode) {
+				t.Fatalf("Expected created bank recorded, got %+v", entry)
+			}
+		}
+	})
+}
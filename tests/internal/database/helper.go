@@ -1,7 +1,7 @@
 package database
 
 import (
-	"SWIFT-Remitly/internal/models"
+	"SWIFT-Remitly/internal/database/migrations"
 	"context"
 	"fmt"
 	"github.com/testcontainers/testcontainers-go"
@@ -96,13 +96,13 @@ func Setup() {
 	}
 }
 
+// migrate recreates the schema by reverting and applying all migrations.
 func migrate(db *gorm.DB) error {
-	err := db.Migrator().DropTable(&models.TimeZone{}, &models.BankCountry{}, &models.BankName{}, &models.CodeType{}, &models.BankTown{}, &models.BankAddress{}, &models.Bank{})
-	if err != nil {
+	migrator := migrations.New(db)
+	if err := migrator.Reset(); err != nil {
 		return err
 	}
-	err = db.AutoMigrate(&models.TimeZone{}, &models.BankCountry{}, &models.BankName{}, &models.CodeType{}, &models.BankTown{}, &models.BankAddress{}, &models.Bank{})
-	if err != nil {
+	if _, err := migrator.Up(); err != nil {
 		return err
 	}
 	return nil
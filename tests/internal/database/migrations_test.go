@@ -0,0 +1,183 @@
+package database
+
+import (
+	"SWIFT-Remitly/internal/database"
+	"SWIFT-Remitly/internal/database/migrations"
+	"errors"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestMigrations(t *testing.T) {
+	db := GetDb()
+	Setup()
+	migrator := migrations.New(db)
+
+	t.Run("All migrations applied", func(t *testing.T) {
+		statuses, err := migrator.Status()
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if len(statuses) == 0 {
+			t.Fatalf("expected migrations, got none")
+		}
+		for _, status := range statuses {
+			if !status.Applied || !status.ChecksumMatch {
+				t.Fatalf("expected migration %d_%s to be applied with matching checksum", status.Version, status.Name)
+			}
+		}
+	})
+
+	t.Run("Up keeps data", func(t *testing.T) {
+		applied, err := migrator.Up()
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if applied != 0 {
+			t.Fatalf("expected 0 applied migrations, got %d", applied)
+		}
+		var count int64
+		if err := db.Table("banks").Count(&count).Error; err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if count == 0 {
+			t.Fatalf("expected banks to be kept")
+		}
+	})
+
+	t.Run("Down and up", func(t *testing.T) {
+		reverted, err := migrator.Down(1)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if reverted != 1 {
+			t.Fatalf("expected 1 reverted migration, got %d", reverted)
+		}
+		applied, err := migrator.Up()
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if applied != 1 {
+			t.Fatalf("expected 1 applied migration, got %d", applied)
+		}
+	})
+
+	t.Run("Pending migrations", func(t *testing.T) {
+		if _, err := migrator.Down(1); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		pending, err := migrator.Pending()
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		statuses, err := migrator.Status()
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		last := statuses[len(statuses)-1]
+		if len(pending) != 1 || pending[0].Version != last.Version {
+			t.Fatalf("expected pending migration %d_%s, got %+v", last.Version, last.Name, pending)
+		}
+		if _, err := migrator.Up(); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if pending, err := migrator.Pending(); err != nil || len(pending) != 0 {
+			t.Fatalf("expected no pending migrations, got %+v, %v", pending, err)
+		}
+	})
+
+	t.Run("Checksum mismatch", func(t *testing.T) {
+		var record migrations.SchemaMigration
+		if err := db.Order("version").First(&record).Error; err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		checksum := record.Checksum
+		if err := db.Model(&record).Update("checksum", "modified").Error; err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if _, err := migrator.Up(); err == nil {
+			t.Fatalf("expected error, got nil")
+		}
+		statuses, err := migrator.Status()
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if statuses[0].ChecksumMatch {
+			t.Fatalf("expected checksum mismatch of migration %d_%s", statuses[0].Version, statuses[0].Name)
+		}
+		if err := db.Model(&record).Update("checksum", checksum).Error; err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+	})
+
+	t.Run("Unknown applied migration", func(t *testing.T) {
+		if err := db.Create(&migrations.SchemaMigration{Version: 9999, Name: "unknown", Checksum: "unknown"}).Error; err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if _, err := migrator.Up(); err == nil {
+			t.Fatalf("expected error, got nil")
+		}
+		if err := db.Delete(&migrations.SchemaMigration{}, 9999).Error; err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+	})
+
+	t.Run("Baseline database adopted", func(t *testing.T) {
+		Setup()
+		statuses, err := migrator.Status()
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		// only the baseline tables are kept, with the country index of databases created before versioned migrations
+		if _, err := migrator.Down(len(statuses) - 1); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		for _, statement := range []string{
+			"ALTER TABLE bank_countries DROP CONSTRAINT uni_bank_countries_iso2_code",
+			"CREATE UNIQUE INDEX idx_country_iso2_code ON bank_countries (iso2_code, country_name)",
+			"DELETE FROM schema_migrations",
+		} {
+			if err := db.Exec(statement).Error; err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+		}
+		if _, err := migrator.Up(); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		var indexes int64
+		if err := db.Raw("SELECT count(*) FROM pg_indexes WHERE indexname = 'idx_country_iso2_code'").Scan(&indexes).Error; err != nil || indexes != 0 {
+			t.Fatalf("expected composite country index to be dropped, got %v, %v", indexes, err)
+		}
+		var constraints int64
+		if err := db.Raw("SELECT count(*) FROM pg_constraint WHERE conname = 'uni_bank_countries_iso2_code'").Scan(&constraints).Error; err != nil || constraints != 1 {
+			t.Fatalf("expected unique ISO2 code constraint, got %v, %v", constraints, err)
+		}
+	})
+
+	t.Run("History of stored banks backfilled", func(t *testing.T) {
+		Setup()
+		srv := database.New(db)
+		if err := srv.DeleteBankBySwiftCode("AAISALTRXXX"); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		// reverting the migrations down to the one adding bank_changes drops the recorded history
+		if _, err := migrator.Down(2); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if _, err := migrator.Up(); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		bank, err := srv.GetBankAsOf("BREXPLPWXXX", time.Now())
+		if err != nil || bank.Name.Name != "UsedInMultipleBanks" {
+			t.Fatalf("expected bank stored before the migration, got %+v, %v", bank, err)
+		}
+		if _, err := srv.GetBankAsOf("AAISALTRXXX", time.Now()); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("expected %v for deleted bank, got %v", gorm.ErrRecordNotFound, err)
+		}
+	})
+
+	Setup()
+}
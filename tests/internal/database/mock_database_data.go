@@ -120,9 +120,9 @@ func prepareAddresses(db *gorm.DB) error {
 
 func prepareBanks(db *gorm.DB) error {
 	sampleBanks = []models.Bank{
-		{SWIFTCode: "BREXPLPWXXX", CodeTypeID: sampleCodeTypes[0].ID, NameID: sampleBankNames[0].ID, AddressID: sampleAddresses[0].ID, CountryID: sampleBankCountries[0].ID, TimeZoneID: sampleTimeZones[0].ID},
-		{SWIFTCode: "AAISALTRXXX", CodeTypeID: sampleCodeTypes[1].ID, NameID: sampleBankNames[1].ID, AddressID: sampleAddresses[0].ID, CountryID: sampleBankCountries[1].ID, TimeZoneID: sampleTimeZones[1].ID},
-		{SWIFTCode: "ALBPPLP1BMW", CodeTypeID: sampleCodeTypes[0].ID, NameID: sampleBankNames[2].ID, AddressID: sampleAddresses[4].ID, CountryID: sampleBankCountries[0].ID, TimeZoneID: sampleTimeZones[0].ID},
+		{SWIFTCode: "BREXPLPWXXX", CodeTypeID: sampleCodeTypes[0].ID, NameID: sampleBankNames[0].ID, AddressID: sampleAddresses[0].ID, CountryID: sampleBankCountries[0].ID, TimeZoneID: &sampleTimeZones[0].ID},
+		{SWIFTCode: "AAISALTRXXX", CodeTypeID: sampleCodeTypes[1].ID, NameID: sampleBankNames[1].ID, AddressID: sampleAddresses[0].ID, CountryID: sampleBankCountries[1].ID, TimeZoneID: &sampleTimeZones[1].ID},
+		{SWIFTCode: "ALBPPLP1BMW", CodeTypeID: sampleCodeTypes[0].ID, NameID: sampleBankNames[2].ID, AddressID: sampleAddresses[4].ID, CountryID: sampleBankCountries[0].ID, TimeZoneID: &sampleTimeZones[0].ID},
 	}
 
 	for i := range sampleBanks {
@@ -133,8 +133,8 @@ func prepareBanks(db *gorm.DB) error {
 		}
 	}
 	sampleBranches := []models.Bank{
-		{SWIFTCode: "BREXPLPWWRO", CodeTypeID: sampleCodeTypes[0].ID, NameID: sampleBankNames[0].ID, AddressID: sampleAddresses[1].ID, CountryID: sampleBankCountries[0].ID, TimeZoneID: sampleTimeZones[0].ID, HeadquarterID: &sampleBanks[0].ID},
-		{SWIFTCode: "BREXPLPWWAL", CodeTypeID: sampleCodeTypes[0].ID, NameID: sampleBankNames[0].ID, AddressID: sampleAddresses[2].ID, CountryID: sampleBankCountries[0].ID, TimeZoneID: sampleTimeZones[0].ID, HeadquarterID: &sampleBanks[0].ID}}
+		{SWIFTCode: "BREXPLPWWRO", CodeTypeID: sampleCodeTypes[0].ID, NameID: sampleBankNames[0].ID, AddressID: sampleAddresses[1].ID, CountryID: sampleBankCountries[0].ID, TimeZoneID: &sampleTimeZones[0].ID, HeadquarterID: &sampleBanks[0].ID},
+		{SWIFTCode: "BREXPLPWWAL", CodeTypeID: sampleCodeTypes[0].ID, NameID: sampleBankNames[0].ID, AddressID: sampleAddresses[2].ID, CountryID: sampleBankCountries[0].ID, TimeZoneID: &sampleTimeZones[0].ID, HeadquarterID: &sampleBanks[0].ID}}
 
 	for i := range sampleBranches {
 		if err := db.Raw("INSERT INTO banks (swift_code, code_type_id, name_id, address_id, country_id, time_zone_id, headquarter_id) VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id",
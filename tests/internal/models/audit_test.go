@@ -0,0 +1,91 @@
+package models_test
+
+import (
+	"SWIFT-Remitly/internal/models"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseAuditQuery(t *testing.T) {
+	testCases := []struct {
+		name      string
+		swiftCode string
+		actor     string
+		from      string
+		to        string
+		limit     string
+		expected  models.AuditQuery
+		valid     bool
+	}{
+		{"No filters", "", "", "", "", "", models.AuditQuery{Limit: models.DefaultAuditLimit}, true},
+		{
+			"All filters", "BREXPLPW", "admin", "2024-01-01T00:00:00Z", "2024-02-01T00:00:00+01:00", "10",
+			models.AuditQuery{
+				SWIFTCode: "BREXPLPWXXX",
+				Actor:     "admin",
+				From:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				To:        time.Date(2024, 1, 31, 23, 0, 0, 0, time.UTC),
+				Limit:     10,
+			},
+			true,
+		},
+		{"Invalid SWIFT code", "BREX", "", "", "", "", models.AuditQuery{}, false},
+		{"Date instead of time", "", "", "2024-01-01", "", "", models.AuditQuery{}, false},
+		{"From after to", "", "", "2024-02-01T00:00:00Z", "2024-01-01T00:00:00Z", "", models.AuditQuery{}, false},
+		{"Limit too large", "", "", "", "", "1001", models.AuditQuery{}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := models.ParseAuditQuery(tc.swiftCode, tc.actor, tc.from, tc.to, tc.limit)
+			if (err == nil) != tc.valid {
+				t.Fatalf("Name: %v, expected valid %v, got %v", tc.name, tc.valid, err)
+			}
+			if query.SWIFTCode != tc.expected.SWIFTCode || query.Actor != tc.expected.Actor ||
+				!query.From.Equal(tc.expected.From) || !query.To.Equal(tc.expected.To) || query.Limit != tc.expected.Limit {
+				t.Fatalf("Name: %v, expected %+v, got %+v", tc.name, tc.expected, query)
+			}
+		})
+	}
+}
+
+func TestAuditPayload(t *testing.T) {
+	bank := models.Bank{
+		SWIFTCode: "BREXPLPWXXX",
+		Name:      models.BankName{Name: "MBANK S.A."},
+		Country:   models.BankCountry{ISO2Code: "PL", CountryName: "POLAND"},
+		Branches:  []models.Bank{{SWIFTCode: "BREXPLPWWAW"}},
+	}
+	payload, err := models.NewAuditPayload(bank)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	entry := models.AuditEntry{Action: models.AuditDelete, Before: payload}
+	data, err := json.Marshal(&entry)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	var decoded struct {
+		Before map[string]interface{} `json:"before"`
+		After  map[string]interface{} `json:"after"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if decoded.Before["swiftCode"] != "BREXPLPWXXX" || decoded.Before["codeType"] != "" || decoded.Before["branches"] != nil {
+		t.Fatalf("expected bank in the full view without branches, got %s", data)
+	}
+	if decoded.After != nil {
+		t.Fatalf("expected null after payload, got %s", data)
+	}
+
+	var scanned models.AuditPayload
+	if err := scanned.Scan([]byte(payload)); err != nil || string(scanned) != string(payload) {
+		t.Fatalf("expected scanned payload %s, got %s, %v", payload, scanned, err)
+	}
+	if value, err := (models.AuditPayload(nil)).Value(); value != nil || err != nil {
+		t.Fatalf("expected NULL value, got %v, %v", value, err)
+	}
+}
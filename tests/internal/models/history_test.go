@@ -0,0 +1,57 @@
+package models_test
+
+import (
+	"SWIFT-Remitly/internal/models"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseAsOf(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected time.Time
+		valid    bool
+	}{
+		{"", time.Time{}, true},
+		{"2024-01-02T15:04:05Z", time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC), true},
+		{"2024-01-02", time.Date(2024, 1, 2, 23, 59, 59, 999999000, time.UTC), true},
+		{"2024-13-02", time.Time{}, false},
+		{"yesterday", time.Time{}, false},
+	}
+
+	for _, tc := range testCases {
+		asOf, err := models.ParseAsOf(tc.value)
+		if (err == nil) != tc.valid {
+			t.Fatalf("Value: %q, expected valid %v, got %v", tc.value, tc.valid, err)
+		}
+		if !asOf.Equal(tc.expected) {
+			t.Fatalf("Value: %q, expected %v, got %v", tc.value, tc.expected, asOf)
+		}
+	}
+}
+
+func TestBankChangeToBank(t *testing.T) {
+	change := models.BankChange{
+		SWIFTCode:   "BREXPLPWXXX",
+		Operation:   models.ChangeUpdated,
+		BankName:    "MBANK S.A.",
+		Address:     "PROSTA 18",
+		TownName:    "WARSZAWA",
+		ISO2Code:    "PL",
+		CountryName: "POLAND",
+		CodeType:    "BIC11",
+		TimeZone:    "Europe/Warsaw",
+	}
+	bank := change.ToBank()
+	bank.View = models.BankViewFull
+
+	data, err := json.Marshal(&bank)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	expected := `{"address":"PROSTA 18","bankName":"MBANK S.A.","countryISO2":"PL","countryName":"POLAND","isHeadquarter":true,"swiftCode":"BREXPLPWXXX","codeType":"BIC11","townName":"WARSZAWA","timeZone":"Europe/Warsaw"}`
+	if string(data) != expected {
+		t.Fatalf("expected %s, got %s", expected, data)
+	}
+}
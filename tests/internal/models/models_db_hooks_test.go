@@ -9,6 +9,10 @@ import (
 	"testing"
 )
 
+func uintPtr(value uint) *uint {
+	return &value
+}
+
 type beforeDeleteTestCase struct {
 	testName  string
 	valueName string
@@ -181,9 +185,32 @@ func TestBeforeCreateBankCountry(t *testing.T) {
 	testCases := []beforeCreateTestCase{
 		{
 			testName:    "ValidBankCountry",
-			input:       &models.BankCountry{ISO2Code: "OK", CountryName: "OK COUNTRY"},
+			input:       &models.BankCountry{ISO2Code: "DE", CountryName: "GERMANY"},
+			expectError: false,
+		},
+		{
+			testName:    "ValidBankCountryAlias",
+			input:       &models.BankCountry{ISO2Code: "CZ", CountryName: "CZECH REPUBLIC"},
 			expectError: false,
 		},
+		{
+			testName:    "UnknownISO2Code",
+			input:       &models.BankCountry{ISO2Code: "OK", CountryName: "GERMANY"},
+			expectError: true,
+			expectedErr: &models.ErrInvalidData{},
+		},
+		{
+			testName:    "UnknownCountryName",
+			input:       &models.BankCountry{ISO2Code: "BG", CountryName: "BULGARIA REP"},
+			expectError: true,
+			expectedErr: &models.ErrInvalidData{},
+		},
+		{
+			testName:    "CountryNameNotMatchingISO2",
+			input:       &models.BankCountry{ISO2Code: "DE", CountryName: "POLAND"},
+			expectError: true,
+			expectedErr: &models.ErrInvalidData{},
+		},
 		{
 			testName:    "InvalidISO2Length",
 			input:       &models.BankCountry{ISO2Code: "OKK", CountryName: "TOOLONG COUNTRY"},
@@ -198,13 +225,13 @@ func TestBeforeCreateBankCountry(t *testing.T) {
 		},
 		{
 			testName:    "InvalidCountryLength",
-			input:       &models.BankCountry{ISO2Code: "OK", CountryName: ""},
+			input:       &models.BankCountry{ISO2Code: "DE", CountryName: ""},
 			expectError: true,
 			expectedErr: &models.ErrInvalidData{},
 		},
 		{
 			testName:    "InvalidCountryCase",
-			input:       &models.BankCountry{ISO2Code: "OK", CountryName: "wrong country"},
+			input:       &models.BankCountry{ISO2Code: "DE", CountryName: "germany"},
 			expectError: true,
 			expectedErr: &models.ErrInvalidData{},
 		},
@@ -231,22 +258,52 @@ func TestBeforeCreateBankName(t *testing.T) {
 	runBeforeCreateTests(t, testCases)
 }
 
+func TestBeforeCreateTimeZone(t *testing.T) {
+	testCases := []beforeCreateTestCase{
+		{
+			testName:    "ValidTimeZone",
+			input:       &models.TimeZone{TimeZone: "Europe/Warsaw"},
+			expectError: false,
+		},
+		{
+			testName:    "EmptyTimeZone",
+			input:       &models.TimeZone{TimeZone: ""},
+			expectError: true,
+			expectedErr: &models.ErrInvalidData{},
+		},
+		{
+			testName:    "UnknownTimeZone",
+			input:       &models.TimeZone{TimeZone: "Europe/Atlantis"},
+			expectError: true,
+			expectedErr: &models.ErrInvalidData{},
+		},
+	}
+
+	runBeforeCreateTests(t, testCases)
+}
+
 func TestBeforeCreateBank(t *testing.T) {
 	testCases := []beforeCreateTestCase{
 		{
 			testName:    "ValidBank",
-			input:       &models.Bank{SWIFTCode: "TESTTESTXXX", CodeTypeID: 1, NameID: 1, AddressID: 1, CountryID: 1, TimeZoneID: 1},
+			input:       &models.Bank{SWIFTCode: "TESTPLPWXXX", CodeTypeID: 1, NameID: 1, AddressID: 1, CountryID: 1, TimeZoneID: uintPtr(1)},
 			expectError: false,
 		},
 		{
 			testName:    "InvalidSWIFTCodeLength",
-			input:       &models.Bank{SWIFTCode: "BREXPLP", CodeTypeID: 1, NameID: 1, AddressID: 1, CountryID: 1, TimeZoneID: 1},
+			input:       &models.Bank{SWIFTCode: "BREXPLP", CodeTypeID: 1, NameID: 1, AddressID: 1, CountryID: 1, TimeZoneID: uintPtr(1)},
+			expectError: true,
+			expectedErr: &models.ErrInvalidData{},
+		},
+		{
+			testName:    "SWIFTCodeCountryNotMatchingBankCountry",
+			input:       &models.Bank{SWIFTCode: "TESTUSNYXXX", CodeTypeID: 1, NameID: 1, AddressID: 1, CountryID: 1, TimeZoneID: uintPtr(1)},
 			expectError: true,
 			expectedErr: &models.ErrInvalidData{},
 		},
 		{
 			testName:    "InvalidSWIFTCodeCase",
-			input:       &models.Bank{SWIFTCode: "brexplpwxxx", CodeTypeID: 1, NameID: 1, AddressID: 1, CountryID: 1, TimeZoneID: 1},
+			input:       &models.Bank{SWIFTCode: "brexplpwxxx", CodeTypeID: 1, NameID: 1, AddressID: 1, CountryID: 1, TimeZoneID: uintPtr(1)},
 			expectError: true,
 			expectedErr: &models.ErrInvalidData{},
 		},
@@ -264,7 +321,7 @@ func TestAfterCreateBankHQBank(t *testing.T) {
 		NameID:     1,
 		AddressID:  1,
 		CountryID:  1,
-		TimeZoneID: 1,
+		TimeZoneID: uintPtr(1),
 	}
 
 	if err := db.Create(&HQBank).Error; err != nil {
@@ -298,7 +355,7 @@ func TestAfterCreateBankBranchBank(t *testing.T) {
 		NameID:     1,
 		AddressID:  1,
 		CountryID:  1,
-		TimeZoneID: 1,
+		TimeZoneID: uintPtr(1),
 	}
 
 	if err := db.Create(&branchBank).Error; err != nil {
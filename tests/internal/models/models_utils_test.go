@@ -4,8 +4,12 @@ import (
 	"SWIFT-Remitly/internal/models"
 	"encoding/json"
 	"errors"
+	"gorm.io/gorm"
 	"log"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 )
 
 type isHeadquarterBankTestCase struct {
@@ -128,6 +132,51 @@ func TestBankMarshalJSON(t *testing.T) {
 			},
 			expected: `{"address":"Main St","bankName":"Main Bank","countryISO2":"US","countryName":"UNITED STATES","isHeadquarter":true,"swiftCode":"TESTTESTXXX","branches":[]}`,
 		},
+		{
+			name: "Headquarter bank requested by BIC8 SWIFT code",
+			bank: models.Bank{
+				Address:           models.BankAddress{Address: "Main St", Town: models.BankTown{Town: "Main Town"}},
+				Name:              models.BankName{Name: "Main Bank"},
+				Country:           models.BankCountry{ISO2Code: "US", CountryName: "UNITED STATES"},
+				SWIFTCode:         "TESTTESTXXX",
+				OriginalSWIFTCode: "TESTTEST",
+				Branches:          []models.Bank{},
+			},
+			expected: `{"address":"Main St","bankName":"Main Bank","countryISO2":"US","countryName":"UNITED STATES","isHeadquarter":true,"swiftCode":"TESTTESTXXX","originalSwiftCode":"TESTTEST","branches":[]}`,
+		},
+		{
+			name: "Headquarter bank with branches in full view",
+			bank: models.Bank{
+				Address:   models.BankAddress{Address: "Main St", Town: models.BankTown{Town: "Main Town"}},
+				Name:      models.BankName{Name: "Main Bank"},
+				Country:   models.BankCountry{ISO2Code: "US", CountryName: "UNITED STATES"},
+				SWIFTCode: "TESTTESTXXX",
+				TimeZone:  models.TimeZone{TimeZone: "UTC"},
+				CodeType:  models.CodeType{CodeType: "BIC11"},
+				View:      models.BankViewFull,
+				Branches: []models.Bank{
+					{
+						Address:   models.BankAddress{Address: "Branch St"},
+						Name:      models.BankName{Name: "Branch 1"},
+						Country:   models.BankCountry{ISO2Code: "US", CountryName: "UNITED STATES"},
+						CodeType:  models.CodeType{CodeType: "BIC11"},
+						SWIFTCode: "TESTTESTNOT",
+					},
+				},
+			},
+			expected: `{"address":"Main St","bankName":"Main Bank","countryISO2":"US","countryName":"UNITED STATES","isHeadquarter":true,"swiftCode":"TESTTESTXXX","codeType":"BIC11","townName":"Main Town","timeZone":"UTC","branches":[{"address":"Branch St","bankName":"Branch 1","countryISO2":"US","countryName":"UNITED STATES","isHeadquarter":false,"swiftCode":"TESTTESTNOT","codeType":"BIC11","townName":"","timeZone":""}]}`,
+		},
+		{
+			name: "Deleted bank",
+			bank: models.Bank{
+				Address:   models.BankAddress{Address: "Main St", Town: models.BankTown{Town: "Main Town"}},
+				Name:      models.BankName{Name: "Main Bank"},
+				Country:   models.BankCountry{ISO2Code: "US", CountryName: "UNITED STATES"},
+				SWIFTCode: "TESTTESTNOT",
+				DeletedAt: gorm.DeletedAt{Time: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), Valid: true},
+			},
+			expected: `{"address":"Main St","bankName":"Main Bank","countryISO2":"US","countryName":"UNITED STATES","isHeadquarter":false,"swiftCode":"TESTTESTNOT","deletedAt":"2025-01-02T03:04:05Z"}`,
+		},
 		{
 			name: "Headquarter bank with empty Country/CountryName without branches",
 			bank: models.Bank{
@@ -182,7 +231,76 @@ func TestUnmarshalJSON(t *testing.T) {
 				"bankName": "Main Bank",
 				"countryISO2": "US",
 				"countryName": "UNITED STATES",
-				"swiftCode": "TESTTESTXXX",
+				"swiftCode": "TESTUSNYXXX",
+				"isHeadquarter": true
+			}`,
+			expected: models.CreateBankRequest{
+				Address:       "Main St",
+				BankName:      "Main Bank",
+				ISO2Code:      "US",
+				CountryName:   "UNITED STATES",
+				SWIFTCode:     "TESTUSNYXXX",
+				CodeType:      "BIC11",
+				IsHeadquarter: true,
+			},
+			expectError: false,
+		},
+		{
+			name: "Valid JSON data with BIC8 SWIFT code",
+			jsonData: `{
+				"address": "Main St",
+				"bankName": "Main Bank",
+				"countryISO2": "US",
+				"countryName": "UNITED STATES",
+				"swiftCode": "TESTUSNY",
+				"isHeadquarter": true
+			}`,
+			expected: models.CreateBankRequest{
+				Address:           "Main St",
+				BankName:          "Main Bank",
+				ISO2Code:          "US",
+				CountryName:       "UNITED STATES",
+				SWIFTCode:         "TESTUSNYXXX",
+				CodeType:          "BIC11",
+				IsHeadquarter:     true,
+				OriginalSWIFTCode: "TESTUSNY",
+			},
+			expectError: false,
+		},
+		{
+			name: "Valid JSON data with BIC8 SWIFT code and code type",
+			jsonData: `{
+				"address": "Main St",
+				"bankName": "Main Bank",
+				"countryISO2": "US",
+				"countryName": "UNITED STATES",
+				"swiftCode": "TESTUSNY",
+				"codeType": "BIC8",
+				"isHeadquarter": true
+			}`,
+			expected: models.CreateBankRequest{
+				Address:           "Main St",
+				BankName:          "Main Bank",
+				ISO2Code:          "US",
+				CountryName:       "UNITED STATES",
+				SWIFTCode:         "TESTUSNYXXX",
+				CodeType:          "BIC11",
+				IsHeadquarter:     true,
+				OriginalSWIFTCode: "TESTUSNY",
+			},
+			expectError: false,
+		},
+		{
+			name: "Valid JSON data with town and time zone",
+			jsonData: `{
+				"address": "Main St",
+				"bankName": "Main Bank",
+				"countryISO2": "US",
+				"countryName": "UNITED STATES",
+				"swiftCode": "TESTUSNYXXX",
+				"codeType": "BIC11",
+				"townName": "NEW YORK",
+				"timeZone": "America/New_York",
 				"isHeadquarter": true
 			}`,
 			expected: models.CreateBankRequest{
@@ -190,11 +308,78 @@ func TestUnmarshalJSON(t *testing.T) {
 				BankName:      "Main Bank",
 				ISO2Code:      "US",
 				CountryName:   "UNITED STATES",
-				SWIFTCode:     "TESTTESTXXX",
+				SWIFTCode:     "TESTUSNYXXX",
+				CodeType:      "BIC11",
+				TownName:      "NEW YORK",
+				TimeZone:      "America/New_York",
+				IsHeadquarter: true,
+			},
+			expectError: false,
+		},
+		{
+			name: "Time zone derived from country",
+			jsonData: `{
+				"address": "Main St",
+				"bankName": "Main Bank",
+				"countryISO2": "PL",
+				"countryName": "POLAND",
+				"swiftCode": "BREXPLPWXXX",
+				"isHeadquarter": true
+			}`,
+			expected: models.CreateBankRequest{
+				Address:       "Main St",
+				BankName:      "Main Bank",
+				ISO2Code:      "PL",
+				CountryName:   "POLAND",
+				SWIFTCode:     "BREXPLPWXXX",
+				CodeType:      "BIC11",
+				TimeZone:      "Europe/Warsaw",
 				IsHeadquarter: true,
 			},
 			expectError: false,
 		},
+		{
+			name: "Code type not matching SWIFT code",
+			jsonData: `{
+				"address": "Main St",
+				"bankName": "Main Bank",
+				"countryISO2": "US",
+				"countryName": "UNITED STATES",
+				"swiftCode": "TESTUSNYXXX",
+				"codeType": "BIC8",
+				"isHeadquarter": true
+			}`,
+			expected:    models.CreateBankRequest{},
+			expectError: true,
+		},
+		{
+			name: "Time zone of another country",
+			jsonData: `{
+				"address": "Main St",
+				"bankName": "Main Bank",
+				"countryISO2": "US",
+				"countryName": "UNITED STATES",
+				"swiftCode": "TESTUSNYXXX",
+				"timeZone": "Europe/Warsaw",
+				"isHeadquarter": true
+			}`,
+			expected:    models.CreateBankRequest{},
+			expectError: true,
+		},
+		{
+			name: "Town name with surrounding whitespace",
+			jsonData: `{
+				"address": "Main St",
+				"bankName": "Main Bank",
+				"countryISO2": "US",
+				"countryName": "UNITED STATES",
+				"swiftCode": "TESTUSNYXXX",
+				"townName": " NEW YORK",
+				"isHeadquarter": true
+			}`,
+			expected:    models.CreateBankRequest{},
+			expectError: true,
+		},
 		{
 			name: "Wrong isHeadquarter value",
 			jsonData: `{
@@ -202,12 +387,38 @@ func TestUnmarshalJSON(t *testing.T) {
 				"bankName": "Main Bank",
 				"countryISO2": "US",
 				"countryName": "UNITED STATES",
-				"swiftCode": "TESTTESTXXX",
+				"swiftCode": "TESTUSNYXXX",
 				"isHeadquarter": false
 			}`,
 			expected:    models.CreateBankRequest{},
 			expectError: true,
 		},
+		{
+			name: "SWIFT code country not matching countryISO2 value",
+			jsonData: `{
+				"address": "Main St",
+				"bankName": "Main Bank",
+				"countryISO2": "PL",
+				"countryName": "POLAND",
+				"swiftCode": "AFAAUYM1XXX",
+				"isHeadquarter": true
+			}`,
+			expected:    models.CreateBankRequest{},
+			expectError: true,
+		},
+		{
+			name: "Country name not matching countryISO2 value",
+			jsonData: `{
+				"address": "Main St",
+				"bankName": "Main Bank",
+				"countryISO2": "US",
+				"countryName": "POLAND",
+				"swiftCode": "TESTUSNYXXX",
+				"isHeadquarter": true
+			}`,
+			expected:    models.CreateBankRequest{},
+			expectError: true,
+		},
 		{
 			name: "Wrong countryISO2 value",
 			jsonData: `{
@@ -215,7 +426,7 @@ func TestUnmarshalJSON(t *testing.T) {
 				"bankName": "Main Bank",
 				"countryISO2": "xxx",
 				"countryName": "UNITED STATES",
-				"swiftCode": "TESTTESTXXX",
+				"swiftCode": "TESTUSNYXXX",
 				"isHeadquarter": true
 			}`,
 			expected:    models.CreateBankRequest{},
@@ -228,7 +439,7 @@ func TestUnmarshalJSON(t *testing.T) {
 				"bankName": "Main Bank",
 				"countryISO2": "xxx",
 				"countryName": "united states",
-				"swiftCode": "TESTTESTXXX",
+				"swiftCode": "TESTUSNYXXX",
 				"isHeadquarter": true
 			}`,
 			expected:    models.CreateBankRequest{},
@@ -241,7 +452,7 @@ func TestUnmarshalJSON(t *testing.T) {
 				"bankName": "Main Bank",
 				"countryISO2": "xxx",
 				"countryName": "united states",
-				"swiftCode": "testtestxx",
+				"swiftCode": "testusnyxx",
 				"isHeadquarter": true
 			}`,
 			expected:    models.CreateBankRequest{},
@@ -254,7 +465,7 @@ func TestUnmarshalJSON(t *testing.T) {
 				"bankName": null,
 				"countryISO2": "US",
 				"countryName": "UNITED STATES",
-				"swiftCode": "TESTTESTXXX",
+				"swiftCode": "TESTUSNYXXX",
 				"isHeadquarter": true
 			}`,
 			expected:    models.CreateBankRequest{},
@@ -264,3 +475,232 @@ func TestUnmarshalJSON(t *testing.T) {
 
 	runUnmarshalJSONTests(t, testCases)
 }
+
+type applyToTestCase struct {
+	name     string
+	update   models.UpdateBankRequest
+	expected models.CreateBankRequest
+}
+
+func runApplyToTests(t *testing.T, base models.CreateBankRequest, testCases []applyToTestCase) {
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := tc.update.ApplyTo(base)
+			if result != tc.expected {
+				t.Errorf("ApplyTo() = %+v, want %+v", result, tc.expected)
+			}
+		})
+	}
+}
+
+func TestApplyTo(t *testing.T) {
+	base := models.CreateBankRequest{
+		Address:       "Main St",
+		BankName:      "Main Bank",
+		ISO2Code:      "US",
+		CountryName:   "UNITED STATES",
+		SWIFTCode:     "TESTUSNYXXX",
+		TownName:      "Main Town",
+		IsHeadquarter: true,
+	}
+	newAddress := "Second St"
+	branchCode := "TESTUSNYBRA"
+	bic8Code := "BANKUSNY"
+	isHeadquarter := true
+	polandCode := "PL"
+	polandName := "POLAND"
+	newTown := "NEW YORK"
+	newTimeZone := "America/New_York"
+
+	testCases := []applyToTestCase{
+		{
+			name:     "Empty update",
+			update:   models.UpdateBankRequest{},
+			expected: base,
+		},
+		{
+			name:   "Update address",
+			update: models.UpdateBankRequest{Address: &newAddress},
+			expected: models.CreateBankRequest{
+				Address: "Second St", BankName: "Main Bank", ISO2Code: "US", CountryName: "UNITED STATES",
+				SWIFTCode: "TESTUSNYXXX", TownName: "Main Town", IsHeadquarter: true,
+			},
+		},
+		{
+			name:   "Update SWIFT code derives headquarter status",
+			update: models.UpdateBankRequest{SWIFTCode: &branchCode},
+			expected: models.CreateBankRequest{
+				Address: "Main St", BankName: "Main Bank", ISO2Code: "US", CountryName: "UNITED STATES",
+				SWIFTCode: "TESTUSNYBRA", CodeType: "BIC11", TownName: "Main Town", IsHeadquarter: false,
+			},
+		},
+		{
+			name:   "Update SWIFT code with provided headquarter status",
+			update: models.UpdateBankRequest{SWIFTCode: &branchCode, IsHeadquarter: &isHeadquarter},
+			expected: models.CreateBankRequest{
+				Address: "Main St", BankName: "Main Bank", ISO2Code: "US", CountryName: "UNITED STATES",
+				SWIFTCode: "TESTUSNYBRA", CodeType: "BIC11", TownName: "Main Town", IsHeadquarter: true,
+			},
+		},
+		{
+			name:   "Update SWIFT code with BIC8",
+			update: models.UpdateBankRequest{SWIFTCode: &bic8Code},
+			expected: models.CreateBankRequest{
+				Address: "Main St", BankName: "Main Bank", ISO2Code: "US", CountryName: "UNITED STATES",
+				SWIFTCode: "BANKUSNYXXX", CodeType: "BIC11", TownName: "Main Town", IsHeadquarter: true, OriginalSWIFTCode: "BANKUSNY",
+			},
+		},
+		{
+			name:   "Update country derives time zone",
+			update: models.UpdateBankRequest{ISO2Code: &polandCode, CountryName: &polandName},
+			expected: models.CreateBankRequest{
+				Address: "Main St", BankName: "Main Bank", ISO2Code: "PL", CountryName: "POLAND",
+				SWIFTCode: "TESTUSNYXXX", TownName: "Main Town", TimeZone: "Europe/Warsaw", IsHeadquarter: true,
+			},
+		},
+		{
+			name:   "Update town and time zone",
+			update: models.UpdateBankRequest{TownName: &newTown, TimeZone: &newTimeZone},
+			expected: models.CreateBankRequest{
+				Address: "Main St", BankName: "Main Bank", ISO2Code: "US", CountryName: "UNITED STATES",
+				SWIFTCode: "TESTUSNYXXX", TownName: "NEW YORK", TimeZone: "America/New_York", IsHeadquarter: true,
+			},
+		},
+	}
+
+	runApplyToTests(t, base, testCases)
+}
+
+func TestSameBankData(t *testing.T) {
+	base := models.CreateBankRequest{
+		Address:     "Main St",
+		BankName:    "Main Bank",
+		ISO2Code:    "US",
+		CountryName: "UNITED STATES",
+		SWIFTCode:   "TESTUSNYXXX",
+		CodeType:    "BIC11",
+		TownName:    "Main Town",
+		TimeZone:    "America/New_York",
+	}
+
+	testCases := []struct {
+		name     string
+		modify   func(request *models.CreateBankRequest)
+		expected bool
+	}{
+		{"Identical data", func(request *models.CreateBankRequest) {}, true},
+		{"Different headquarter flag", func(request *models.CreateBankRequest) { request.IsHeadquarter = true }, true},
+		{"Different original SWIFT code", func(request *models.CreateBankRequest) { request.OriginalSWIFTCode = "TESTUSNY" }, true},
+		{"Different address", func(request *models.CreateBankRequest) { request.Address = "Second St" }, false},
+		{"Different code type", func(request *models.CreateBankRequest) { request.CodeType = "BIC8" }, false},
+		{"Different town", func(request *models.CreateBankRequest) { request.TownName = "Second Town" }, false},
+		{"Different time zone", func(request *models.CreateBankRequest) { request.TimeZone = "" }, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			other := base
+			tc.modify(&other)
+			if result := base.SameBankData(other); result != tc.expected {
+				t.Fatalf("Name: %v, expected %v, got %v", tc.name, tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestNewLookupResponse(t *testing.T) {
+	banks := map[string]models.Bank{
+		"BREXPLPWXXX": {SWIFTCode: "BREXPLPWXXX", Name: models.BankName{Name: "MBANK S.A."}},
+		"BREXPLPWWAW": {SWIFTCode: "BREXPLPWWAW", Name: models.BankName{Name: "MBANK S.A."}},
+	}
+
+	response := models.NewLookupResponse([]string{"BREXPLPWWAW", "BREXPLPW", "AAAAPLPWXXX", "brexplpwxxx", "BREXPLPWWAW"}, banks)
+	if response.Found != 2 || response.NotFound != 1 || response.Invalid != 1 || len(response.Results) != 4 {
+		t.Fatalf("expected 2 found, 1 not found and 1 invalid SWIFT code, got %+v", response)
+	}
+
+	if result := response.Results["BREXPLPW"]; result.Bank == nil || result.Bank.SWIFTCode != "BREXPLPWXXX" || result.Bank.OriginalSWIFTCode != "BREXPLPW" {
+		t.Fatalf("expected BREXPLPWXXX found by BIC8 code, got %+v", result)
+	}
+	if result := response.Results["AAAAPLPWXXX"]; result.Bank != nil || result.Error == nil || result.Error.Status != http.StatusNotFound {
+		t.Fatalf("expected AAAAPLPWXXX not found, got %+v", result)
+	}
+	if result := response.Results["brexplpwxxx"]; result.Bank != nil || result.Error == nil || result.Error.Status != http.StatusBadRequest || len(result.Error.Details) == 0 {
+		t.Fatalf("expected brexplpwxxx invalid, got %+v", result)
+	}
+
+	data, err := json.Marshal(&response)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if !strings.Contains(string(data), `"bankName":"MBANK S.A."`) {
+		t.Fatalf("expected banks in the API format, got %s", data)
+	}
+}
+
+func TestNewBatchResponse(t *testing.T) {
+	requests := []models.CreateBankRequest{{SWIFTCode: "BREXPLPWXXX"}, {SWIFTCode: "BREXPLPWWAW"}, {SWIFTCode: "AAAAPLPWXXX", OriginalSWIFTCode: "AAAAPLPW"}}
+	errs := []error{nil, gorm.ErrDuplicatedKey, &models.ErrRequestInvalid{Message: "Request invalid", Details: []string{"invalid"}}}
+
+	t.Run("Best effort", func(t *testing.T) {
+		response := models.NewBatchResponse(false, requests, errs)
+		if response.Status != http.StatusMultiStatus || response.Created != 1 || response.Failed != 2 {
+			t.Fatalf("expected status 207 with 1 created and 2 failed banks, got %+v", response)
+		}
+		for i, expected := range []int{http.StatusCreated, http.StatusConflict, http.StatusBadRequest} {
+			if result := response.Results[i]; result.Status != expected || result.SWIFTCode != requests[i].SWIFTCode {
+				t.Fatalf("expected result %d with status %d, got %+v", i, expected, result)
+			}
+		}
+		if response.Results[2].OriginalSWIFTCode != "AAAAPLPW" {
+			t.Fatalf("expected original SWIFT code of the request, got %+v", response.Results[2])
+		}
+	})
+
+	t.Run("Atomic with failing bank", func(t *testing.T) {
+		response := models.NewBatchResponse(true, requests, errs)
+		if response.Status != http.StatusConflict || response.Created != 0 || response.Failed != 2 {
+			t.Fatalf("expected status of the first failed bank without created banks, got %+v", response)
+		}
+		if result := response.Results[0]; result.Success || result.Status != http.StatusFailedDependency {
+			t.Fatalf("expected bank not added because of the failed banks, got %+v", result)
+		}
+	})
+
+	t.Run("Atomic", func(t *testing.T) {
+		response := models.NewBatchResponse(true, requests[:1], errs[:1])
+		if response.Status != http.StatusCreated || response.Created != 1 || !response.Results[0].Success {
+			t.Fatalf("expected status 201 with 1 created bank, got %+v", response)
+		}
+	})
+}
+
+func TestUpdateBankRequestValidate(t *testing.T) {
+	// stored data of other sources is not checked unless the update provides it
+	stored := models.CreateBankRequest{
+		Address: "Main St", BankName: "Main Bank", ISO2Code: "US", CountryName: "UNITED STATES",
+		SWIFTCode: "TESTUSNYXXX", CodeType: "CodeType1", TownName: " Main Town", TimeZone: "Timezone1", IsHeadquarter: true,
+	}
+	newAddress := "Second St"
+	codeType := "BIC8"
+	timeZone := "Europe/Warsaw"
+
+	testCases := []struct {
+		name     string
+		update   models.UpdateBankRequest
+		expected bool
+	}{
+		{"Update of other fields", models.UpdateBankRequest{Address: &newAddress}, true},
+		{"Code type not matching SWIFT code", models.UpdateBankRequest{CodeType: &codeType}, false},
+		{"Time zone of another country", models.UpdateBankRequest{TimeZone: &timeZone}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.update.Validate(tc.update.ApplyTo(stored))
+			if (err == nil) != tc.expected {
+				t.Fatalf("Name: %v, expected valid %v, got %v", tc.name, tc.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"SWIFT-Remitly/internal/models"
+	"errors"
+	"testing"
+)
+
+type parsePageRequestTestCase struct {
+	name     string
+	limit    string
+	cursor   string
+	sort     string
+	expected bool
+	page     models.PageRequest
+}
+
+func runParsePageRequestTests(t *testing.T, testCases []parsePageRequestTestCase) {
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := models.ParsePageRequest(tc.limit, tc.cursor, tc.sort)
+			if tc.expected {
+				if err != nil {
+					t.Fatalf("Name: %v, expected nil, got %v", tc.name, err)
+				}
+				if page.Limit != tc.page.Limit || page.Sort != tc.page.Sort {
+					t.Fatalf("Name: %v, expected %+v, got %+v", tc.name, tc.page, page)
+				}
+				if (page.After == nil) != (tc.page.After == nil) || (page.After != nil && *page.After != *tc.page.After) {
+					t.Fatalf("Name: %v, expected cursor %+v, got %+v", tc.name, tc.page.After, page.After)
+				}
+			} else {
+				var errRequestInvalid *models.ErrRequestInvalid
+				if !errors.As(err, &errRequestInvalid) {
+					t.Fatalf("Name: %v, expected %T, got %v", tc.name, errRequestInvalid, err)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePageRequest(t *testing.T) {
+	cursor := models.PageCursor{Sort: models.SortByBankName, Value: "MBANK S.A.", SWIFTCode: "BREXPLPWXXX"}
+	testCases := []parsePageRequestTestCase{
+		{"Defaults", "", "", "", true, models.PageRequest{Sort: models.SortBySWIFTCode}},
+		{"Limit and sort", "10", "", "town", true, models.PageRequest{Limit: 10, Sort: models.SortByTown}},
+		{"Cursor", "", cursor.Encode(), "bankName", true, models.PageRequest{Sort: models.SortByBankName, After: &cursor}},
+		{"Zero limit", "0", "", "", false, models.PageRequest{}},
+		{"Too large limit", "1001", "", "", false, models.PageRequest{}},
+		{"Not a number limit", "ten", "", "", false, models.PageRequest{}},
+		{"Unknown sort", "", "", "address", false, models.PageRequest{}},
+		{"Malformed cursor", "", "not a cursor", "", false, models.PageRequest{}},
+		{"Cursor of another sort", "", cursor.Encode(), "town", false, models.PageRequest{}},
+	}
+	runParsePageRequestTests(t, testCases)
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"SWIFT-Remitly/internal/models"
+	"errors"
+	"testing"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	testCases := []struct {
+		name            string
+		text            string
+		iso2Code        string
+		headquarterOnly string
+		limit           string
+		expected        bool
+		query           models.SearchQuery
+	}{
+		{"Defaults", " mbank ", "", "", "", true, models.SearchQuery{Text: "mbank", Limit: models.DefaultSearchLimit}},
+		{"Filters", "mbank", "PL", "true", "5", true, models.SearchQuery{Text: "mbank", ISO2Code: "PL", HeadquarterOnly: true, Limit: 5}},
+		{"Too short text", "m", "", "", "", false, models.SearchQuery{}},
+		{"Invalid country", "mbank", "POL", "", "", false, models.SearchQuery{}},
+		{"Invalid headquarterOnly", "mbank", "", "yes please", "", false, models.SearchQuery{}},
+		{"Too large limit", "mbank", "", "", "101", false, models.SearchQuery{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := models.ParseSearchQuery(tc.text, tc.iso2Code, "", tc.headquarterOnly, tc.limit)
+			if tc.expected {
+				if err != nil {
+					t.Fatalf("Name: %v, expected nil, got %v", tc.name, err)
+				}
+				if query != tc.query {
+					t.Fatalf("Name: %v, expected %+v, got %+v", tc.name, tc.query, query)
+				}
+				return
+			}
+			var errRequestInvalid *models.ErrRequestInvalid
+			if !errors.As(err, &errRequestInvalid) {
+				t.Fatalf("Name: %v, expected %T, got %v", tc.name, errRequestInvalid, err)
+			}
+		})
+	}
+}
+
+func TestNewSearchResult(t *testing.T) {
+	bank := models.Bank{
+		SWIFTCode: "BREXPLPWXXX",
+		Name:      models.BankName{Name: "MBANK S.A. & CO"},
+		Address:   models.BankAddress{Address: "UL. PROSTA 18", Town: models.BankTown{Town: "WARSZAWA"}},
+	}
+
+	result := models.NewSearchResult(bank, 0.5, "mbank co warsz")
+	expected := map[string]string{
+		"bankName": "<em>MBANK</em> S.A. &amp; <em>CO</em>",
+		"townName": "<em>WARSZ</em>AWA",
+	}
+	if len(result.Highlights) != len(expected) {
+		t.Fatalf("expected highlights %v, got %v", expected, result.Highlights)
+	}
+	for field, highlighted := range expected {
+		if result.Highlights[field] != highlighted {
+			t.Fatalf("expected %v highlight %q, got %q", field, highlighted, result.Highlights[field])
+		}
+	}
+	if result.Rank != 0.5 || result.Bank.SWIFTCode != bank.SWIFTCode {
+		t.Fatalf("expected ranked bank %v, got %+v", bank.SWIFTCode, result)
+	}
+}
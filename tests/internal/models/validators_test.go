@@ -9,7 +9,7 @@ import (
 type testValidateCase struct {
 	name          string
 	code          string
-	extra         any //Additional parameter for specific validations (e.g. bool for HQ)
+	extra         any //Additional parameter for specific validations (e.g. bool for HQ, ISO2 code for SWIFT country)
 	expected      bool
 	detailsLength int
 }
@@ -24,6 +24,8 @@ func runTestValidateCases(t *testing.T, testCases []testValidateCase, validateFu
 				err = f(tc.code)
 			case func(string, bool) error:
 				err = f(tc.code, tc.extra.(bool))
+			case func(string, string) error:
+				err = f(tc.code, tc.extra.(string))
 			default:
 				t.Fatalf("Unsupported validation function type")
 			}
@@ -47,16 +49,42 @@ func runTestValidateCases(t *testing.T, testCases []testValidateCase, validateFu
 
 func TestValidateSWIFTCode(t *testing.T) {
 	testCases := []testValidateCase{
-		{"Valid SWIFT code", "TESTTESTXXX", nil, true, 0},
-		{"SWIFT code too short", "TESTTESTXX", nil, false, 1},
-		{"SWIFT code too long", "TESTTESTXXXX", nil, false, 1},
-		{"SWIFT code not uppercase", "testtestxxx", nil, false, 1},
-		{"SWIFT code not uppercase and too long", "TESTTESTxxxA", nil, false, 2},
-		{"SWIFT code not uppercase and too short", "testtestxx", nil, false, 2},
+		{"Valid SWIFT code", "TESTPLPWXXX", nil, true, 0},
+		{"SWIFT code too short", "TESTPLPWXX", nil, false, 1},
+		{"SWIFT code too long", "TESTPLPWXXXX", nil, false, 1},
+		{"SWIFT code not uppercase", "testplpwxxx", nil, false, 1},
+		{"SWIFT code not uppercase and too long", "TESTPLPWxxxA", nil, false, 2},
+		{"SWIFT code not uppercase and too short", "testplpwxx", nil, false, 2},
+		{"Valid BIC8 SWIFT code", "TESTPLPW", nil, true, 0},
+		{"BIC8 SWIFT code not uppercase", "testplpw", nil, false, 1},
+		{"BIC8 SWIFT code with invalid country code", "TESTTYPW", nil, false, 1},
+		{"Valid SWIFT code of passive participant", "TESTPLP1XXX", nil, true, 0},
+		{"Valid SWIFT code with reverse billing", "TESTPLP2WAW", nil, true, 0},
+		{"Institution code with digits", "1234PLPWXXX", nil, false, 1},
+		{"Country code with digits", "TEST12PWXXX", nil, false, 1},
+		{"Country code not in ISO 3166-1", "QWERTYUIOPA", nil, false, 1},
+		{"Location code with special character", "TESTPLP-XXX", nil, false, 1},
+		{"Location code starting with 0", "TESTPL0WXXX", nil, false, 1},
+		{"Location code ending with letter O", "TESTPLWOXXX", nil, false, 1},
+		{"Valid SWIFT code of test BIC", "TESTPLW0XXX", nil, true, 0},
+		{"Branch code with special character", "TESTPLPW-AB", nil, false, 1},
+		{"Branch code starting with X", "ADAAAGS1XAA", nil, false, 1},
+		{"Multiple invalid segments", "1234TY00XAA", nil, false, 4},
 	}
 	runTestValidateCases(t, testCases, models.ValidateSWIFTCode)
 }
 
+func TestValidateInstitutionCode(t *testing.T) {
+	testCases := []testValidateCase{
+		{"Valid institution code", "TESTPLPW", nil, true, 0},
+		{"Institution code of SWIFT code", "TESTPLPWXXX", nil, false, 1},
+		{"Institution code too short", "TESTPLP", nil, false, 1},
+		{"Institution code not uppercase", "testplpw", nil, false, 1},
+		{"Institution code with invalid country code", "TESTTYPW", nil, false, 1},
+	}
+	runTestValidateCases(t, testCases, models.ValidateInstitutionCode)
+}
+
 func TestValidateISO2Code(t *testing.T) {
 	testCases := []testValidateCase{
 		{"Valid ISO2 code", "US", nil, true, 0},
@@ -65,6 +93,7 @@ func TestValidateISO2Code(t *testing.T) {
 		{"ISO2 code not uppercase", "us", nil, false, 1},
 		{"ISO2 code not uppercase and too long", "USa", nil, false, 2},
 		{"ISO2 code not uppercase and too short", "u", nil, false, 2},
+		{"ISO2 code not in ISO 3166-1", "XX", nil, false, 1},
 	}
 	runTestValidateCases(t, testCases, models.ValidateISO2Code)
 }
@@ -74,10 +103,24 @@ func TestValidateCountryName(t *testing.T) {
 		{"Valid country name", "UNITED STATES", nil, true, 0},
 		{"Empty country name", "", nil, false, 1},
 		{"Country name not uppercase", "United States", nil, false, 1},
+		{"Country name alias", "UNITED STATES OF AMERICA", nil, true, 0},
+		{"Country name not in ISO 3166-1", "BULGARIA REP", nil, false, 1},
+		{"Country name not in ISO 3166-1 and not uppercase", "Bulgaria Rep", nil, false, 2},
 	}
 	runTestValidateCases(t, testCases, models.ValidateCountryName)
 }
 
+func TestValidateCountry(t *testing.T) {
+	testCases := []testValidateCase{
+		{"Matching country name", "BG", "BULGARIA", true, 0},
+		{"Matching country alias", "US", "USA", true, 0},
+		{"Not matching country name", "PL", "GERMANY", false, 1},
+		{"Unknown country name", "BG", "BULGARIA REP", true, 0},
+		{"Unknown ISO2 code", "XX", "BULGARIA", true, 0},
+	}
+	runTestValidateCases(t, testCases, models.ValidateCountry)
+}
+
 func TestValidateBankName(t *testing.T) {
 	testCases := []testValidateCase{
 		{"Valid bank name", "Main Bank", nil, true, 0},
@@ -88,10 +131,82 @@ func TestValidateBankName(t *testing.T) {
 
 func TestValidateHeadquarter(t *testing.T) {
 	testCases := []testValidateCase{
-		{"Valid headquarter", "TESTTESTXXX", true, true, 0},
-		{"Valid branch", "TESTTESTNOT", false, true, 0},
-		{"HQ SWIFT, Branch flag", "TESTTESTXXX", false, false, 1},
-		{"Branch SWIFT, HQ flag", "TESTTESTNOT", true, false, 1},
+		{"Valid headquarter", "TESTPLPWXXX", true, true, 0},
+		{"Valid branch", "TESTPLPWNOT", false, true, 0},
+		{"Valid BIC8 headquarter", "TESTPLPW", true, true, 0},
+		{"BIC8 SWIFT, Branch flag", "TESTPLPW", false, false, 1},
+		{"HQ SWIFT, Branch flag", "TESTPLPWXXX", false, false, 1},
+		{"Branch SWIFT, HQ flag", "TESTPLPWNOT", true, false, 1},
 	}
 	runTestValidateCases(t, testCases, models.ValidateHeadquarter)
 }
+
+func TestValidateSWIFTCountry(t *testing.T) {
+	testCases := []testValidateCase{
+		{"Matching country", "BREXPLPWXXX", "PL", true, 0},
+		{"Matching country BIC8", "BREXPLPW", "PL", true, 0},
+		{"Not matching country", "AFAAUYM1XXX", "PL", false, 1},
+		{"Territory using GB SWIFT codes", "TESTGB2LXXX", "JE", true, 0},
+		{"GB bank using territory SWIFT code", "TESTJESHXXX", "GB", false, 1},
+		{"Too short SWIFT code", "TEST", "PL", true, 0},
+	}
+	runTestValidateCases(t, testCases, models.ValidateSWIFTCountry)
+}
+
+func TestValidateTimeZone(t *testing.T) {
+	testCases := []testValidateCase{
+		{"Valid time zone", "Europe/Warsaw", nil, true, 0},
+		{"Valid time zone abbreviation", "CET", nil, true, 0},
+		{"Empty time zone", "", nil, false, 1},
+		{"Unknown time zone", "Europe/Atlantis", nil, false, 1},
+		{"Local time zone", "Local", nil, false, 1},
+	}
+	runTestValidateCases(t, testCases, models.ValidateTimeZone)
+}
+
+func TestValidateTimeZoneCountry(t *testing.T) {
+	testCases := []testValidateCase{
+		{"Time zone of the country", "Europe/Warsaw", "PL", true, 0},
+		{"One of time zones of the country", "Pacific/Easter", "CL", true, 0},
+		{"Time zone shared with other countries", "Europe/Paris", "MC", true, 0},
+		{"Time zone alias", "Europe/Monaco", "MC", true, 0},
+		{"Time zone alias of a shared time zone", "America/Aruba", "AW", true, 0},
+		{"Renamed time zone", "Europe/Kiev", "UA", true, 0},
+		{"Time zone of another country", "America/New_York", "PL", false, 1},
+		{"Time zone of another country with the same offsets", "Europe/Berlin", "PL", false, 1},
+		{"Time zone alias of another country", "Europe/Oslo", "PL", false, 1},
+		{"Unknown time zone", "Europe/Atlantis", "PL", true, 0},
+		{"Unknown country", "Europe/Warsaw", "XX", true, 0},
+	}
+	runTestValidateCases(t, testCases, models.ValidateTimeZoneCountry)
+}
+
+func TestValidateCodeType(t *testing.T) {
+	testCases := []testValidateCase{
+		{"BIC11 code type", "BIC11", "TESTPLPWXXX", true, 0},
+		{"BIC8 code type", "BIC8", "TESTPLPW", true, 0},
+		{"Code type not matching SWIFT code", "BIC8", "TESTPLPWXXX", false, 1},
+		{"Unknown code type", "BIC12", "TESTPLPWXXX", false, 1},
+		{"Lowercase code type", "bic11", "TESTPLPWXXX", false, 1},
+		{"Empty code type", "", "TESTPLPWXXX", false, 1},
+		{"Invalid SWIFT code", "BIC11", "TEST", true, 0},
+	}
+	runTestValidateCases(t, testCases, models.ValidateCodeType)
+}
+
+func TestValidateTownName(t *testing.T) {
+	testCases := []testValidateCase{
+		{"Valid town name", "WARSZAWA", nil, true, 0},
+		{"Empty town name", "", nil, true, 0},
+		{"Town name with surrounding whitespace", " WARSZAWA ", nil, false, 1},
+	}
+	runTestValidateCases(t, testCases, models.ValidateTownName)
+}
+
+func TestDefaultTimeZone(t *testing.T) {
+	for iso2Code, expected := range map[string]string{"PL": "Europe/Warsaw", "MC": "Europe/Paris", "US": "", "XX": ""} {
+		if timeZone := models.DefaultTimeZone(iso2Code); timeZone != expected {
+			t.Fatalf("Country: %v, expected %q, got %q", iso2Code, expected, timeZone)
+		}
+	}
+}
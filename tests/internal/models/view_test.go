@@ -0,0 +1,35 @@
+package models_test
+
+import (
+	"SWIFT-Remitly/internal/models"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseBankView(t *testing.T) {
+	for name, expected := range map[string]bool{"": true, "full": true, "FULL": false, "compact": false} {
+		if _, err := models.ParseBankView(name); (err == nil) != expected {
+			t.Fatalf("View: %q, expected valid %v, got %v", name, expected, err)
+		}
+	}
+}
+
+func TestSetView(t *testing.T) {
+	bank := models.Bank{
+		SWIFTCode: "BREXPLPWXXX",
+		CodeType:  models.CodeType{CodeType: "BIC11"},
+		Address:   models.BankAddress{Town: models.BankTown{Town: "WARSZAWA"}},
+		TimeZone:  models.TimeZone{TimeZone: "Europe/Warsaw"},
+	}
+	institution := models.Institution{Headquarter: &bank, Branches: []models.Bank{bank}}
+	institution.SetView(models.BankViewFull)
+
+	data, err := json.Marshal(&institution)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if count := strings.Count(string(data), `"timeZone":"Europe/Warsaw"`); count != 2 {
+		t.Fatalf("expected full view of headquarter and branch, got %s", data)
+	}
+}
@@ -0,0 +1,83 @@
+package parser_test
+
+import (
+	"SWIFT-Remitly/internal/models"
+	"SWIFT-Remitly/internal/parser"
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	albanianBank := models.Bank{
+		SWIFTCode: "AAISALTRXXX",
+		CodeType:  models.CodeType{CodeType: "BIC11"},
+		Name:      models.BankName{Name: "UNITED BANK OF ALBANIA SH.A"},
+		Address:   models.BankAddress{Address: "HYRJA 3 RR. DRITAN HOXHA ND. 11 TIRANA, TIRANA, 1023", Town: models.BankTown{Town: "TIRANA"}},
+		Country:   models.BankCountry{ISO2Code: "AL", CountryName: "ALBANIA"},
+	}
+	db := &MockService{storedBanks: map[string]models.Bank{
+		"BREXPLPWXXX": storedBank("BREXPLPWXXX", "MBANK S.A."),
+		"BREXPLPWWAW": storedBank("BREXPLPWWAW", "MBANK S.A., \"WARSZAWA\""),
+		"AAISALTRXXX": albanianBank,
+	}}
+
+	testCases := []struct {
+		name          string
+		format        parser.Format
+		iso2Code      string
+		expectedBanks int
+	}{
+		{"CSV", parser.FormatCSV, "", 3},
+		{"NDJSON", parser.FormatNDJSON, "", 3},
+		{"JSON", parser.FormatJSON, "", 3},
+		{"CSV of a country", parser.FormatCSV, "PL", 2},
+		{"Empty CSV", parser.FormatCSV, "DE", 0},
+		{"Empty JSON", parser.FormatJSON, "DE", 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			if err := parser.Export(db, buf, tc.format, tc.iso2Code); err != nil {
+				t.Fatalf("Name: %v, expected nil, got %v", tc.name, err)
+			}
+
+			// importing the export leaves the stored bank data unchanged
+			path := createTempFile(t, "."+string(tc.format), buf.Bytes())
+			defer func() {
+				if err := os.Remove(path); err != nil {
+					log.Printf("Failed to remove temp file: %v", err)
+				}
+			}()
+			report, err := parser.ParseCSV(db, path, parser.WithDryRun())
+			if err != nil {
+				t.Fatalf("Name: %v, expected nil, got %v\n%s", tc.name, err, buf)
+			}
+			if expected := (models.ImportSummary{Unchanged: tc.expectedBanks}); report.ImportSummary != expected {
+				t.Fatalf("Name: %v, expected summary %+v, got %+v\n%s", tc.name, expected, report.ImportSummary, buf)
+			}
+		})
+	}
+
+	t.Run("Banks ordered by SWIFT code", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		if err := parser.Export(db, buf, parser.FormatNDJSON, ""); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 3 || !strings.Contains(lines[0], "AAISALTRXXX") || !strings.Contains(lines[2], "BREXPLPWXXX") {
+			t.Fatalf("expected banks ordered by SWIFT code, got %v", lines)
+		}
+	})
+}
+
+func TestParseExportFormat(t *testing.T) {
+	for name, expected := range map[string]bool{"": true, "csv": true, "NDJSON": true, "json": true, "xlsx": false, "xml": false} {
+		if _, err := parser.ParseExportFormat(name); (err == nil) != expected {
+			t.Fatalf("Format: %q, expected valid %v, got %v", name, expected, err)
+		}
+	}
+}
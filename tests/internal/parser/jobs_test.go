@@ -0,0 +1,137 @@
+package parser_test
+
+import (
+	"SWIFT-Remitly/internal/models"
+	"SWIFT-Remitly/internal/parser"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func csvContent(t *testing.T, headers []string, data [][]string) *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	writer := csv.NewWriter(buf)
+	if err := writer.Write(headers); err != nil {
+		t.Fatalf("failed to write headers: %v", err)
+	}
+	if err := writer.WriteAll(data); err != nil {
+		t.Fatalf("failed to write data: %v", err)
+	}
+	return buf
+}
+
+func waitForJob(t *testing.T, jobs *parser.ImportJobs, id string) models.ImportJob {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := jobs.Get(id)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if job.Status != models.ImportRunning {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("import job %s did not finish", id)
+	return models.ImportJob{}
+}
+
+func TestImportJobs(t *testing.T) {
+	data := [][]string{
+		{"AL", "AAISALTRXXX", "BIC11", "UNITED BANK OF ALBANIA SH.A", "HYRJA 3 RR. DRITAN HOXHA ND. 11 TIRANA, TIRANA, 1023", "TIRANA", "ALBANIA", "Europe/Tirane"},
+		{"PL", "TESTPLPWXXX"},
+		{"BG", "ABIEBGS1XXX", "BIC11", "ABV INVESTMENTS LTD", "TSAR ASEN 20  VARNA, VARNA, 9002", "VARNA", "BULGARIA", "Europe/Sofia"},
+	}
+
+	jobs := parser.NewImportJobs(&MockService{upsertResults: map[string]models.UpsertResult{"ABIEBGS1XXX": models.UpsertUpdated}})
+	job, err := jobs.Start("banks.csv", csvContent(t, correctHeaders, data))
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if job.ID == "" || job.FileName != "banks.csv" || job.TotalRows != len(data) {
+		t.Fatalf("expected started job of banks.csv with %d rows, got %+v", len(data), job)
+	}
+
+	job = waitForJob(t, jobs, job.ID)
+	if job.Status != models.ImportCompleted || job.FinishedAt == nil || job.Report == nil {
+		t.Fatalf("expected completed job with report, got %+v", job)
+	}
+	expectedSummary := models.ImportSummary{Inserted: 1, Updated: 1, Failed: 1}
+	if job.Progress != expectedSummary || job.Report.ImportSummary != expectedSummary {
+		t.Fatalf("expected summary %+v, got progress %+v and report %+v", expectedSummary, job.Progress, job.Report.ImportSummary)
+	}
+	if len(job.Report.Rejected) != 1 || job.Report.Rejected[0].Line != 3 {
+		t.Fatalf("expected rejected line 3, got %+v", job.Report.Rejected)
+	}
+}
+
+func TestImportJobsInvalidFile(t *testing.T) {
+	jobs := parser.NewImportJobs(&MockService{})
+
+	_, err := jobs.Start("banks.csv", csvContent(t, incorrectHeaders, nil))
+	var errRequestInvalid *models.ErrRequestInvalid
+	if !errors.As(err, &errRequestInvalid) {
+		t.Fatalf("expected %T, got %v", errRequestInvalid, err)
+	}
+
+	if _, err := jobs.Get("missing"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected %v, got %v", gorm.ErrRecordNotFound, err)
+	}
+}
+
+func TestImportJobsRemoveOldestFinished(t *testing.T) {
+	data := [][]string{
+		{"AL", "AAISALTRXXX", "BIC11", "UNITED BANK OF ALBANIA SH.A", "HYRJA 3 RR. DRITAN HOXHA ND. 11 TIRANA, TIRANA, 1023", "TIRANA", "ALBANIA", "Europe/Tirane"},
+	}
+	jobs := parser.NewImportJobs(&MockService{})
+
+	// one job more than the number of kept finished jobs
+	ids := make([]string, 101)
+	for i := range ids {
+		job, err := jobs.Start("banks.csv", csvContent(t, correctHeaders, data))
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		waitForJob(t, jobs, job.ID)
+		ids[i] = job.ID
+	}
+
+	if _, err := jobs.Get(ids[0]); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected oldest job removed, got %v", err)
+	}
+	if _, err := jobs.Get(ids[1]); err != nil {
+		t.Fatalf("expected second job kept, got %v", err)
+	}
+}
+
+func TestParseCSVProgress(t *testing.T) {
+	tmpFile := createMockCSV(correctHeaders, [][]string{
+		{"AL", "AAISALTRXXX", "BIC11", "UNITED BANK OF ALBANIA SH.A", "HYRJA 3 RR. DRITAN HOXHA ND. 11 TIRANA, TIRANA, 1023", "TIRANA", "ALBANIA", "Europe/Tirane"},
+		{"PL", "TESTPLPWXXX"},
+	})
+	if err := tmpFile.Close(); err != nil {
+		t.Fatalf("failed to close temp file: %v", err)
+	}
+	defer func() {
+		if err := os.Remove(tmpFile.Name()); err != nil {
+			log.Printf("Failed to remove temp file: %v", err)
+		}
+	}()
+
+	var updates []models.ImportSummary
+	_, err := parser.ParseCSV(&MockService{}, tmpFile.Name(), parser.WithProgress(func(summary models.ImportSummary) {
+		updates = append(updates, summary)
+	}))
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if len(updates) != 2 || updates[1] != (models.ImportSummary{Inserted: 1, Failed: 1}) {
+		t.Fatalf("expected progress after every row, got %+v", updates)
+	}
+}
@@ -1,17 +1,32 @@
 package parser_test
 
 import (
+	"SWIFT-Remitly/internal/database"
 	"SWIFT-Remitly/internal/models"
 	"SWIFT-Remitly/internal/parser"
 	"bytes"
+	"context"
 	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // MockService is a mock implementation of the database.Service interface
-type MockService struct{}
+// Upserted banks are reported as inserted, unless listed in upsertResults or upsertErrors
+// Banks listed in storedBanks are returned by GetBanksBySwiftCodes and ExportBanks
+type MockService struct {
+	upsertResults map[string]models.UpsertResult
+	upsertErrors  map[string]error
+	storedBanks   map[string]models.Bank
+}
 
 func (m *MockService) Close() error {
 	return nil
@@ -21,7 +36,35 @@ func (m *MockService) GetBankBySwiftCode(swiftCode string) (models.Bank, error)
 	return models.Bank{}, nil
 }
 
-func (m *MockService) GetBanksByISO2Code(iso2Code string) (models.CountrySWIFTCode, error) {
+func (m *MockService) GetBanksBySwiftCodes(swiftCodes []string) (map[string]models.Bank, error) {
+	banks := map[string]models.Bank{}
+	for _, swiftCode := range swiftCodes {
+		if bank, ok := m.storedBanks[swiftCode]; ok {
+			banks[swiftCode] = bank
+		}
+	}
+	return banks, nil
+}
+
+func (m *MockService) GetInstitution(institutionCode string) (models.Institution, error) {
+	return models.Institution{}, nil
+}
+
+func (m *MockService) ExportBanks(iso2Code string, handleBatch func(banks []models.Bank) error) error {
+	var banks []models.Bank
+	for _, bank := range m.storedBanks {
+		if iso2Code == "" || bank.Country.ISO2Code == iso2Code {
+			banks = append(banks, bank)
+		}
+	}
+	if len(banks) == 0 {
+		return nil
+	}
+	sort.Slice(banks, func(i, j int) bool { return banks[i].SWIFTCode < banks[j].SWIFTCode })
+	return handleBatch(banks)
+}
+
+func (m *MockService) GetBanksByISO2Code(iso2Code string, page models.PageRequest) (models.CountrySWIFTCode, error) {
 	return models.CountrySWIFTCode{}, nil
 }
 
@@ -29,10 +72,86 @@ func (m *MockService) AddBankFromRequest(requestData models.CreateBankRequest) e
 	return nil
 }
 
+func (m *MockService) AddBanksFromRequests(requests []models.CreateBankRequest, mode database.BulkMode) ([]database.BulkResult, error) {
+	return make([]database.BulkResult, len(requests)), nil
+}
+
 func (m *MockService) DeleteBankBySwiftCode(swiftCode string) error {
 	return nil
 }
 
+func (m *MockService) RestoreBankBySwiftCode(swiftCode string) error {
+	return nil
+}
+
+func (m *MockService) GetDeletedBanks() ([]models.Bank, error) {
+	return nil, nil
+}
+
+func (m *MockService) PurgeDeletedBanks(deletedBefore time.Time) (int, error) {
+	return 0, nil
+}
+
+func (m *MockService) UpdateBank(swiftCode string, requestData models.UpdateBankRequest) error {
+	return nil
+}
+
+func (m *MockService) GetCountries() ([]models.CountrySummary, error) {
+	return []models.CountrySummary{}, nil
+}
+
+func (m *MockService) GetBankHistory(swiftCode string) (models.BankHistory, error) {
+	return models.BankHistory{}, nil
+}
+
+func (m *MockService) GetBankAsOf(swiftCode string, asOf time.Time) (models.Bank, error) {
+	return models.Bank{}, nil
+}
+
+func (m *MockService) GetBanksAsOf(swiftCodes []string, asOf time.Time) (map[string]models.Bank, error) {
+	return map[string]models.Bank{}, nil
+}
+
+func (m *MockService) Audited(entry models.AuditEntry, mutation func(db database.Service) (database.AuditResult, error)) error {
+	_, err := mutation(m)
+	return err
+}
+
+func (m *MockService) AddAuditEntry(entry models.AuditEntry) error {
+	return nil
+}
+
+func (m *MockService) GetAuditEntries(query models.AuditQuery) ([]models.AuditEntry, error) {
+	return []models.AuditEntry{}, nil
+}
+
+func (m *MockService) SearchBanks(query models.SearchQuery) ([]models.SearchResult, error) {
+	return []models.SearchResult{}, nil
+}
+
+func (m *MockService) UpsertBankFromRequest(requestData models.CreateBankRequest) (models.UpsertResult, error) {
+	if err, ok := m.upsertErrors[requestData.SWIFTCode]; ok {
+		return 0, err
+	}
+	if result, ok := m.upsertResults[requestData.SWIFTCode]; ok {
+		return result, nil
+	}
+	return models.UpsertInserted, nil
+}
+
+func (m *MockService) BulkUpsertBanks(requests []models.CreateBankRequest, mode database.BulkMode) ([]database.BulkResult, error) {
+	results := make([]database.BulkResult, len(requests))
+	failed := false
+	for i, request := range requests {
+		results[i].Result, results[i].Err = m.UpsertBankFromRequest(request)
+		failed = failed || results[i].Err != nil
+	}
+	if mode == database.BulkAtomic && failed {
+		return results, database.ErrBulkAborted
+	}
+	return results, nil
+}
+
 var (
 	correctHeaders = []string{
 		"COUNTRY ISO2 CODE",
@@ -78,10 +197,15 @@ func createMockCSV(headers []string, data [][]string) *os.File {
 }
 
 type parseCSVTestCase struct {
-	name     string
-	headers  []string
-	data     [][]string
-	expected bool
+	name            string
+	headers         []string
+	data            [][]string
+	upsertResults   map[string]models.UpsertResult
+	upsertErrors    map[string]error
+	expected        bool
+	expectedSummary models.ImportSummary
+	rejectedLines   []int
+	options         []parser.Option
 }
 
 func runTestParseCSV(t *testing.T, testCases []parseCSVTestCase) {
@@ -99,10 +223,25 @@ func runTestParseCSV(t *testing.T, testCases []parseCSVTestCase) {
 				log.Printf("Failed to close temp file: %v", err)
 			}
 
-			err := parser.ParseCSV(&MockService{}, tmpFile.Name())
+			db := &MockService{upsertResults: tc.upsertResults, upsertErrors: tc.upsertErrors}
+			report, err := parser.ParseCSV(db, tmpFile.Name(), tc.options...)
 			if tc.expected && err != nil {
 				log.Fatalf("Name: %v, expected nil, got %v", tc.name, err)
 			}
+			if tc.expected && report.ImportSummary != tc.expectedSummary {
+				t.Fatalf("Name: %v, expected summary %+v, got %+v", tc.name, tc.expectedSummary, report.ImportSummary)
+			}
+			if tc.expected && len(report.Rejected) != len(tc.rejectedLines) {
+				t.Fatalf("Name: %v, expected %d rejected rows, got %+v", tc.name, len(tc.rejectedLines), report.Rejected)
+			}
+			for i, line := range tc.rejectedLines {
+				if report.Rejected[i].Line != line {
+					t.Fatalf("Name: %v, expected rejected line %d, got %d", tc.name, line, report.Rejected[i].Line)
+				}
+				if len(report.Rejected[i].Record) == 0 || report.Rejected[i].Error == "" {
+					t.Fatalf("Name: %v, expected record and error of rejected line %d, got %+v", tc.name, line, report.Rejected[i])
+				}
+			}
 			if !tc.expected && err == nil {
 				log.Fatalf("Name: %v, expected error, got nil", tc.name)
 			}
@@ -121,7 +260,23 @@ func TestParseCSV(t *testing.T) {
 				{"BG", "ABIEBGS1XXX", "BIC11", "ABV INVESTMENTS LTD", "TSAR ASEN 20  VARNA, VARNA, 9002", "VARNA", "BULGARIA", "Europe/Sofia"},
 				{"BG", "ADCRBGS1XXX", "BIC11", "ADAMANT CAPITAL PARTNERS AD", "JAMES BOURCHIER BLVD 76A HILL TOWER SOFIA, SOFIA, 1421", "SOFIA", "BULGARIA", "Europe/Sofia"},
 			},
-			expected: true,
+			expected:        true,
+			expectedSummary: models.ImportSummary{Inserted: 3},
+		},
+		{
+			name:    "Reimported CSV data",
+			headers: correctHeaders,
+			data: [][]string{
+				{"AL", "AAISALTRXXX", "BIC11", "UNITED BANK OF ALBANIA SH.A", "HYRJA 3 RR. DRITAN HOXHA ND. 11 TIRANA, TIRANA, 1023", "TIRANA", "ALBANIA", "Europe/Tirane"},
+				{"BG", "ABIEBGS1XXX", "BIC11", "ABV INVESTMENTS LTD", "TSAR ASEN 20  VARNA, VARNA, 9002", "VARNA", "BULGARIA", "Europe/Sofia"},
+				{"BG", "ADCRBGS1", "BIC11", "ADAMANT CAPITAL PARTNERS AD", "JAMES BOURCHIER BLVD 76A HILL TOWER SOFIA, SOFIA, 1421", "SOFIA", "BULGARIA", "Europe/Sofia"},
+			},
+			upsertResults: map[string]models.UpsertResult{
+				"AAISALTRXXX": models.UpsertUnchanged,
+				"ADCRBGS1XXX": models.UpsertUpdated,
+			},
+			expected:        true,
+			expectedSummary: models.ImportSummary{Inserted: 1, Updated: 1, Unchanged: 1},
 		},
 		{
 			name:    "Invalid CSV data",
@@ -130,9 +285,63 @@ func TestParseCSV(t *testing.T) {
 				{"AL", "AAISALTRXXX", "BIC11", "UNITED BANK OF ALBANIA SH.A", "HYRJA 3 RR. DRITAN HOXHA ND. 11 TIRANA, TIRANA, 1023", "TIRANA", "ALBANIA", "Europe/Tirane"},
 				{"BG", "ABIEBGS1XXX", "BIC11", "ABV INVESTMENTS LTD", "TSAR ASEN 20  VARNA, VARNA, 9002", "VARNA", "BULGARIA", "Europe/Sofia"},
 				{"BG", "ADCRBGS1XXX", "BIC11", "ADAMANT CAPITAL PARTNERS AD", "JAMES BOURCHIER BLVD 76A HILL TOWER SOFIA, SOFIA, 1421", "SOFIA", "BULGARIA"},
-				{"PL", "TESTTESTXXX"},
+				{"PL", "TESTPLPWXXX"},
+				{"PL", "AFAAUYM1XXX", "BIC11", "AFINIDAD A.F.A.P.S.A.", "PLAZA INDEPENDENCIA 743  MONTEVIDEO, MONTEVIDEO, 11000", "MONTEVIDEO", "POLAND", "America/Montevideo"},
+			},
+			expected:        true,
+			expectedSummary: models.ImportSummary{Inserted: 2, Failed: 3},
+			rejectedLines:   []int{4, 5, 6},
+		},
+		{
+			name:    "Database errors",
+			headers: correctHeaders,
+			data: [][]string{
+				{"AL", "AAISALTRXXX", "BIC11", "UNITED BANK OF ALBANIA SH.A", "HYRJA 3 RR. DRITAN HOXHA ND. 11 TIRANA, TIRANA, 1023", "TIRANA", "ALBANIA", "Europe/Tirane"},
+				{"BG", "ABIEBGS1XXX", "BIC11", "ABV INVESTMENTS LTD", "TSAR ASEN 20  VARNA, VARNA, 9002", "VARNA", "BULGARIA", "Europe/Sofia"},
+			},
+			upsertErrors: map[string]error{
+				"ABIEBGS1XXX": &models.ErrInvalidData{Message: "Invalid time zone", Details: []string{"Time zone does not belong to the country"}},
+			},
+			expected:        true,
+			expectedSummary: models.ImportSummary{Inserted: 1, Failed: 1},
+			rejectedLines:   []int{3},
+		},
+		{
+			name:    "Reordered headers with extra column",
+			headers: []string{"SWIFT CODE", "NAME", "EXTRA", "COUNTRY ISO2 CODE", "ADDRESS", "TOWN NAME", "TIME ZONE", "CODE TYPE", "COUNTRY NAME"},
+			data: [][]string{
+				{"AAISALTRXXX", "UNITED BANK OF ALBANIA SH.A", "ignored", "AL", "HYRJA 3 RR. DRITAN HOXHA ND. 11 TIRANA, TIRANA, 1023", "TIRANA", "Europe/Tirane", "BIC11", "ALBANIA"},
+				{"ABIEBGS1XXX", "ABV INVESTMENTS LTD", "ignored", "BG", "TSAR ASEN 20  VARNA, VARNA, 9002", "VARNA", "Europe/Sofia", "BIC11", "BULGARIA"},
+			},
+			expected:        true,
+			expectedSummary: models.ImportSummary{Inserted: 2},
+		},
+		{
+			name:    "Header aliases",
+			headers: []string{"Country Code", "BIC", "Code Type", "Bank Name", "Address", "City", "Country", "Time Zone"},
+			data: [][]string{
+				{"AL", "AAISALTRXXX", "BIC11", "UNITED BANK OF ALBANIA SH.A", "HYRJA 3 RR. DRITAN HOXHA ND. 11 TIRANA, TIRANA, 1023", "TIRANA", "ALBANIA", "Europe/Tirane"},
 			},
-			expected: true,
+			expected:        true,
+			expectedSummary: models.ImportSummary{Inserted: 1},
+		},
+		{
+			name:    "Configured header aliases",
+			headers: []string{"COUNTRY ISO2 CODE", "IDENTIFIER", "CODE TYPE", "NAME", "ADDRESS", "TOWN NAME", "COUNTRY NAME", "TIME ZONE"},
+			data: [][]string{
+				{"AL", "AAISALTRXXX", "BIC11", "UNITED BANK OF ALBANIA SH.A", "HYRJA 3 RR. DRITAN HOXHA ND. 11 TIRANA, TIRANA, 1023", "TIRANA", "ALBANIA", "Europe/Tirane"},
+			},
+			expected:        true,
+			expectedSummary: models.ImportSummary{Inserted: 1},
+			options:         []parser.Option{parser.WithHeaderAliases(map[string]string{"identifier": "SWIFT CODE"})},
+		},
+		{
+			name:    "Duplicated header",
+			headers: []string{"COUNTRY ISO2 CODE", "SWIFT CODE", "BIC", "CODE TYPE", "NAME", "ADDRESS", "TOWN NAME", "COUNTRY NAME", "TIME ZONE"},
+			data: [][]string{
+				{"AL", "AAISALTRXXX", "AAISALTRXXX", "BIC11", "UNITED BANK OF ALBANIA SH.A", "HYRJA 3 RR. DRITAN HOXHA ND. 11 TIRANA, TIRANA, 1023", "TIRANA", "ALBANIA", "Europe/Tirane"},
+			},
+			expected: false,
 		},
 		{
 			name:    "Invalid headers",
@@ -148,3 +357,349 @@ func TestParseCSV(t *testing.T) {
 
 	runTestParseCSV(t, testCases)
 }
+
+func TestParseCSVBulk(t *testing.T) {
+	data := [][]string{
+		{"AL", "AAISALTRXXX", "BIC11", "UNITED BANK OF ALBANIA SH.A", "HYRJA 3 RR. DRITAN HOXHA ND. 11 TIRANA, TIRANA, 1023", "TIRANA", "ALBANIA", "Europe/Tirane"},
+		{"BG", "ABIEBGS1XXX", "BIC11", "ABV INVESTMENTS LTD", "TSAR ASEN 20  VARNA, VARNA, 9002", "VARNA", "BULGARIA", "Europe/Sofia"},
+		{"BG", "ADCRBGS1XXX", "BIC11", "ADAMANT CAPITAL PARTNERS AD", "JAMES BOURCHIER BLVD 76A HILL TOWER SOFIA, SOFIA, 1421", "SOFIA", "BULGARIA", "Europe/Sofia"},
+	}
+	dataWithInvalidRow := append(append([][]string(nil), data...), []string{"PL", "TESTPLPWXXX"})
+	storeErrors := map[string]error{
+		"ABIEBGS1XXX": &models.ErrInvalidData{Message: "Invalid time zone", Details: []string{"Time zone does not belong to the country"}},
+	}
+
+	testCases := []parseCSVTestCase{
+		{
+			name:            "Best effort",
+			headers:         correctHeaders,
+			data:            dataWithInvalidRow,
+			upsertResults:   map[string]models.UpsertResult{"AAISALTRXXX": models.UpsertUpdated},
+			upsertErrors:    storeErrors,
+			expected:        true,
+			expectedSummary: models.ImportSummary{Inserted: 1, Updated: 1, Failed: 2},
+			rejectedLines:   []int{3, 5},
+			options:         []parser.Option{parser.WithBulkImport(database.BulkBestEffort)},
+		},
+		{
+			name:            "Atomic",
+			headers:         correctHeaders,
+			data:            data,
+			expected:        true,
+			expectedSummary: models.ImportSummary{Inserted: 3},
+			options:         []parser.Option{parser.WithBulkImport(database.BulkAtomic)},
+		},
+		{
+			name:     "Atomic with invalid row",
+			headers:  correctHeaders,
+			data:     dataWithInvalidRow,
+			expected: false,
+			options:  []parser.Option{parser.WithBulkImport(database.BulkAtomic)},
+		},
+		{
+			name:         "Atomic with row which cannot be stored",
+			headers:      correctHeaders,
+			data:         data,
+			upsertErrors: storeErrors,
+			expected:     false,
+			options:      []parser.Option{parser.WithBulkImport(database.BulkAtomic)},
+		},
+	}
+
+	runTestParseCSV(t, testCases)
+}
+
+func TestParseCSVRejectsFile(t *testing.T) {
+	data := [][]string{
+		{"AL", "AAISALTRXXX", "BIC11", "UNITED BANK OF ALBANIA SH.A", "HYRJA 3 RR. DRITAN HOXHA ND. 11 TIRANA, TIRANA, 1023", "TIRANA", "ALBANIA", "Europe/Tirane"},
+		{"PL", "TESTPLPWXXX"},
+		{"PL", "AFAAUYM1XXX", "BIC11", "AFINIDAD A.F.A.P.S.A.", "PLAZA INDEPENDENCIA 743  MONTEVIDEO, MONTEVIDEO, 11000", "MONTEVIDEO", "POLAND", "America/Montevideo"},
+	}
+
+	for _, format := range []parser.RejectsFormat{parser.RejectsJSON, parser.RejectsCSV} {
+		t.Run(string(format), func(t *testing.T) {
+			tmpFile := createMockCSV(correctHeaders, data)
+			if err := tmpFile.Close(); err != nil {
+				log.Printf("Failed to close temp file: %v", err)
+			}
+			rejectsPath := strings.TrimSuffix(tmpFile.Name(), ".csv") + ".rejects." + string(format)
+			defer func() {
+				for _, path := range []string{tmpFile.Name(), rejectsPath} {
+					if err := os.Remove(path); err != nil {
+						log.Printf("Failed to remove temp file: %v", err)
+					}
+				}
+			}()
+
+			report, err := parser.ParseCSV(&MockService{}, tmpFile.Name(), parser.WithRejectsFile(format))
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			if report.RejectsFile != rejectsPath {
+				t.Fatalf("expected rejects file %s, got %s", rejectsPath, report.RejectsFile)
+			}
+
+			content, err := os.ReadFile(rejectsPath)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			switch format {
+			case parser.RejectsJSON:
+				var rejected []models.RejectedRow
+				if err := json.Unmarshal(content, &rejected); err != nil {
+					t.Fatalf("expected nil, got %v", err)
+				}
+				if len(rejected) != 2 || rejected[0].Line != 3 || rejected[1].Line != 4 {
+					t.Fatalf("expected rejected lines 3 and 4, got %+v", rejected)
+				}
+				if len(rejected[1].Details) == 0 {
+					t.Fatalf("expected validation details of line 4, got %+v", rejected[1])
+				}
+			case parser.RejectsCSV:
+				records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+				if err != nil {
+					t.Fatalf("expected nil, got %v", err)
+				}
+				if len(records) != 3 {
+					t.Fatalf("expected header and 2 rejected rows, got %v", records)
+				}
+				if len(records[0]) != len(correctHeaders)+2 || records[1][len(correctHeaders)] != "3" {
+					t.Fatalf("expected headers with LINE and ERRORS columns, got %v", records)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRejectsFormat(t *testing.T) {
+	for name, expected := range map[string]bool{"": true, "json": true, "CSV": true, "xml": false} {
+		if _, err := parser.ParseRejectsFormat(name); (err == nil) != expected {
+			t.Fatalf("Name: %q, expected valid %v, got %v", name, expected, err)
+		}
+	}
+}
+
+func TestParseCSVFormat(t *testing.T) {
+	windows1250, err := parser.ParseEncoding("windows-1250")
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	testCases := []struct {
+		name    string
+		content []byte
+		options []parser.Option
+	}{
+		{
+			name:    "Semicolon delimiter",
+			content: []byte(strings.Join(correctHeaders, ";") + "\nPL;BREXPLPWXXX;BIC11;MBANK S.A.;UL. PROSTA 18;WARSZAWA;POLAND;Europe/Warsaw\n"),
+			options: []parser.Option{parser.WithDelimiter(';')},
+		},
+		{
+			name:    "UTF-8 with byte order mark",
+			content: []byte("\uFEFF" + strings.Join(correctHeaders, ",") + "\nPL,BREXPLPWXXX,BIC11,MBANK S.A.,UL. PROSTA 18,WARSZAWA,POLAND,Europe/Warsaw\n"),
+		},
+		{
+			// "ŁÓDŹ" encoded in windows-1250
+			name:    "Windows-1250",
+			content: append([]byte(strings.Join(correctHeaders, ",")+"\nPL,BREXPLPWXXX,BIC11,MBANK S.A.,UL. PROSTA 18,"), append([]byte{0xA3, 0xD3, 0x44, 0x8F}, []byte(",POLAND,Europe/Warsaw\n")...)...),
+			options: []parser.Option{parser.WithEncoding(windows1250)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpFile, err := os.CreateTemp("", "test*.csv")
+			if err != nil {
+				t.Fatalf("failed to create temp file: %v", err)
+			}
+			defer func() {
+				if err := os.Remove(tmpFile.Name()); err != nil {
+					log.Printf("Failed to remove temp file: %v", err)
+				}
+			}()
+			if _, err := tmpFile.Write(tc.content); err != nil {
+				t.Fatalf("failed to write temp file: %v", err)
+			}
+			if err := tmpFile.Close(); err != nil {
+				log.Printf("Failed to close temp file: %v", err)
+			}
+
+			db := &recordingService{institutions: map[string][]string{}}
+			report, err := parser.ParseCSV(db, tmpFile.Name(), tc.options...)
+			if err != nil {
+				t.Fatalf("Name: %v, expected nil, got %v", tc.name, err)
+			}
+			if report.Inserted != 1 || report.Failed != 0 {
+				t.Fatalf("Name: %v, expected 1 inserted row, got %+v", tc.name, report)
+			}
+			if tc.name == "Windows-1250" && db.towns[0] != "ŁÓDŹ" {
+				t.Fatalf("Name: %v, expected town ŁÓDŹ, got %v", tc.name, db.towns)
+			}
+		})
+	}
+}
+
+func TestParseFormatOptions(t *testing.T) {
+	for list, expected := range map[string]bool{"": true, "BIC:SWIFT CODE, city:town name": true, "BIC": false, "BIC:UNKNOWN": false} {
+		if _, err := parser.ParseHeaderAliases(list); (err == nil) != expected {
+			t.Fatalf("Aliases: %q, expected valid %v, got %v", list, expected, err)
+		}
+	}
+	for name, expected := range map[string]bool{"": true, ";": true, "tab": true, "|": true, ";;": false, "\"": false} {
+		if _, err := parser.ParseDelimiter(name); (err == nil) != expected {
+			t.Fatalf("Delimiter: %q, expected valid %v, got %v", name, expected, err)
+		}
+	}
+	for name, expected := range map[string]bool{"": true, "utf-8": true, "UTF-16LE": true, "iso-8859-2": true, "ebcdic": false} {
+		if _, err := parser.ParseEncoding(name); (err == nil) != expected {
+			t.Fatalf("Encoding: %q, expected valid %v, got %v", name, expected, err)
+		}
+	}
+}
+
+// recordingService records the SWIFT codes of upserted banks grouped by institution and their towns
+type recordingService struct {
+	MockService
+	mutex        sync.Mutex
+	institutions map[string][]string
+	towns        []string
+}
+
+func (r *recordingService) UpsertBankFromRequest(requestData models.CreateBankRequest) (models.UpsertResult, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.institutions[requestData.SWIFTCode[:8]] = append(r.institutions[requestData.SWIFTCode[:8]], requestData.SWIFTCode)
+	r.towns = append(r.towns, requestData.TownName)
+	return models.UpsertInserted, nil
+}
+
+func TestParseCSVWorkers(t *testing.T) {
+	var data [][]string
+	expected := map[string][]string{}
+	for i := 0; i < 50; i++ {
+		institution := fmt.Sprintf("%c%cBKPLPW", 'A'+i%26, 'A'+i/26)
+		// branches before their headquarter
+		for _, branch := range []string{"BRA", "WAW", "XXX"} {
+			swiftCode := institution + branch
+			data = append(data, []string{"PL", swiftCode, "BIC11", "BANK", "ADDRESS", "WARSZAWA", "POLAND", "Europe/Warsaw"})
+			expected[institution] = append(expected[institution], swiftCode)
+		}
+	}
+
+	tmpFile := createMockCSV(correctHeaders, data)
+	if err := tmpFile.Close(); err != nil {
+		log.Printf("Failed to close temp file: %v", err)
+	}
+	defer func() {
+		if err := os.Remove(tmpFile.Name()); err != nil {
+			log.Printf("Failed to remove temp file: %v", err)
+		}
+	}()
+
+	db := &recordingService{institutions: map[string][]string{}}
+	report, err := parser.ParseCSV(db, tmpFile.Name(), parser.WithWorkers(8))
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if report.Inserted != len(data) || report.Failed != 0 {
+		t.Fatalf("expected %d inserted rows, got %+v", len(data), report.ImportSummary)
+	}
+	for institution, swiftCodes := range expected {
+		if strings.Join(db.institutions[institution], ",") != strings.Join(swiftCodes, ",") {
+			t.Fatalf("expected banks of %s stored in file order %v, got %v", institution, swiftCodes, db.institutions[institution])
+		}
+	}
+}
+
+func TestParseCSVContextCanceled(t *testing.T) {
+	tmpFile := createMockCSV(correctHeaders, [][]string{
+		{"AL", "AAISALTRXXX", "BIC11", "UNITED BANK OF ALBANIA SH.A", "HYRJA 3 RR. DRITAN HOXHA ND. 11 TIRANA, TIRANA, 1023", "TIRANA", "ALBANIA", "Europe/Tirane"},
+	})
+	if err := tmpFile.Close(); err != nil {
+		log.Printf("Failed to close temp file: %v", err)
+	}
+	defer func() {
+		if err := os.Remove(tmpFile.Name()); err != nil {
+			log.Printf("Failed to remove temp file: %v", err)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err := parser.ParseCSVContext(ctx, &MockService{}, tmpFile.Name(), parser.WithWorkers(4))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+	if report.Inserted != 0 {
+		t.Fatalf("expected no stored rows, got %+v", report.ImportSummary)
+	}
+}
+
+func storedBank(swiftCode string, name string) models.Bank {
+	return models.Bank{
+		SWIFTCode: swiftCode,
+		CodeType:  models.CodeType{CodeType: "BIC11"},
+		Name:      models.BankName{Name: name},
+		Address:   models.BankAddress{Address: "ADDRESS", Town: models.BankTown{Town: "WARSZAWA"}},
+		Country:   models.BankCountry{ISO2Code: "PL", CountryName: "POLAND"},
+		TimeZone:  models.TimeZone{TimeZone: "Europe/Warsaw"},
+	}
+}
+
+func TestParseCSVDryRun(t *testing.T) {
+	data := [][]string{
+		{"PL", "AAAAPLPWXXX", "BIC11", "NEW BANK", "ADDRESS", "WARSZAWA", "POLAND", "Europe/Warsaw"},
+		{"PL", "BBBBPLPWXXX", "BIC11", "STORED BANK", "ADDRESS", "WARSZAWA", "POLAND", "Europe/Warsaw"},
+		{"PL", "CCCCPLPWXXX", "BIC11", "RENAMED BANK", "ADDRESS", "WARSZAWA", "POLAND", "Europe/Warsaw"},
+		{"PL", "AAAAPLPWBRA", "BIC11", "NEW BANK", "ADDRESS", "WARSZAWA", "POLAND", "Europe/Warsaw"},
+		{"PL", "BBBBPLPWBRA", "BIC11", "STORED BANK", "ADDRESS", "WARSZAWA", "POLAND", "Europe/Warsaw"},
+		{"PL", "DDDDPLPWBRA", "BIC11", "ORPHAN BANK", "ADDRESS", "WARSZAWA", "POLAND", "Europe/Warsaw"},
+		{"PL", "AAAAPLPWXXX", "BIC11", "NEW BANK", "ADDRESS", "WARSZAWA", "POLAND", "Europe/Warsaw"},
+		{"PL", "CCCCPLPWXXX", "BIC11", "RENAMED AGAIN BANK", "ADDRESS", "WARSZAWA", "POLAND", "Europe/Warsaw"},
+		{"PL", "EEEEPLPWXXX", "BIC11", "", "ADDRESS", "WARSZAWA", "POLAND", "Europe/Warsaw"},
+	}
+
+	tmpFile := createMockCSV(correctHeaders, data)
+	if err := tmpFile.Close(); err != nil {
+		log.Printf("Failed to close temp file: %v", err)
+	}
+	defer func() {
+		if err := os.Remove(tmpFile.Name()); err != nil {
+			log.Printf("Failed to remove temp file: %v", err)
+		}
+	}()
+
+	// storing any bank would fail, so the dry run must not store them
+	upsertErrors := map[string]error{}
+	for _, row := range data {
+		upsertErrors[row[1]] = errors.New("bank stored during dry run")
+	}
+	db := &MockService{
+		upsertErrors: upsertErrors,
+		storedBanks: map[string]models.Bank{
+			"BBBBPLPWXXX": storedBank("BBBBPLPWXXX", "STORED BANK"),
+			"CCCCPLPWXXX": storedBank("CCCCPLPWXXX", "STORED BANK"),
+		},
+	}
+	report, err := parser.ParseCSV(db, tmpFile.Name(), parser.WithDryRun(), parser.WithBulkImport(database.BulkAtomic),
+		parser.WithRejectsFile(parser.RejectsJSON))
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if report.RejectsFile != "" {
+		t.Fatalf("expected no rejects file in a dry run, got %s", report.RejectsFile)
+	}
+
+	// the repeated AAAAPLPWXXX is unchanged and the repeated CCCCPLPWXXX updated, like by the import
+	expectedSummary := models.ImportSummary{Inserted: 4, Updated: 2, Unchanged: 2, Failed: 1}
+	if !report.DryRun || report.ImportSummary != expectedSummary {
+		t.Fatalf("expected dry run summary %+v, got %+v", expectedSummary, report)
+	}
+	if len(report.Rejected) != 1 || report.Rejected[0].Line != 10 {
+		t.Fatalf("expected rejected line 10, got %+v", report.Rejected)
+	}
+	if strings.Join(report.OrphanBranches, ",") != "DDDDPLPWBRA" {
+		t.Fatalf("expected orphan branch DDDDPLPWBRA, got %v", report.OrphanBranches)
+	}
+}
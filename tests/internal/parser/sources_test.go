@@ -0,0 +1,258 @@
+package parser_test
+
+import (
+	"SWIFT-Remitly/internal/models"
+	"SWIFT-Remitly/internal/parser"
+	"archive/zip"
+	"bytes"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// createTempFile writes the content to a temporary file with the extension and returns its path
+func createTempFile(t *testing.T, extension string, content []byte) string {
+	tmpFile, err := os.CreateTemp("", "test*"+extension)
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	if _, err := tmpFile.Write(content); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		t.Fatalf("failed to close temp file: %v", err)
+	}
+	return tmpFile.Name()
+}
+
+// xlsxContent builds a workbook with a single sheet named Banks. Cells of the first row use shared strings,
+// cells of the other rows inline strings.
+func xlsxContent(t *testing.T, rows [][]string) []byte {
+	var sharedStrings, sheet strings.Builder
+	for i, row := range rows {
+		sheet.WriteString(`<row r="` + strconv.Itoa(i+1) + `">`)
+		for j, text := range row {
+			ref := string(rune('A'+j)) + strconv.Itoa(i+1)
+			if i == 0 {
+				sheet.WriteString(`<c r="` + ref + `" t="s"><v>` + strconv.Itoa(j) + `</v></c>`)
+				sharedStrings.WriteString(`<si><t>` + text + `</t></si>`)
+				continue
+			}
+			sheet.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t>` + text + `</t></is></c>`)
+		}
+		sheet.WriteString(`</row>`)
+	}
+	return xlsxPackage(t, sharedStrings.String(), sheet.String())
+}
+
+// xlsxInlineRow returns the row with the number of cells with inline strings.
+func xlsxInlineRow(number int, texts []string) string {
+	var row strings.Builder
+	row.WriteString(`<row r="` + strconv.Itoa(number) + `">`)
+	for j, text := range texts {
+		ref := string(rune('A'+j)) + strconv.Itoa(number)
+		row.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t>` + text + `</t></is></c>`)
+	}
+	row.WriteString(`</row>`)
+	return row.String()
+}
+
+// xlsxPackage builds a workbook with a single sheet named Banks of the shared strings and rows.
+func xlsxPackage(t *testing.T, sharedStrings string, sheetData string) []byte {
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Banks" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/sharedStrings.xml":     `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` + sharedStrings + `</sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` + sheetData + `</sheetData></worksheet>`,
+	}
+
+	buf := new(bytes.Buffer)
+	archive := zip.NewWriter(buf)
+	for name, content := range parts {
+		part, err := archive.Create(name)
+		if err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+		if _, err := part.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseSources(t *testing.T) {
+	bank := `{"countryISO2": "PL", "swiftCode": "BREXPLPWXXX", "codeType": "BIC11", "bankName": "MBANK S.A.", "address": "UL. PROSTA 18", "townName": "WARSZAWA", "countryName": "POLAND", "timeZone": "Europe/Warsaw"}`
+	branch := `{"countryISO2": "PL", "swiftCode": "BREXPLPWWAW", "codeType": "BIC11", "bankName": "MBANK S.A.", "address": "UL. PROSTA 18", "townName": "WARSZAWA", "countryName": "POLAND", "timeZone": "Europe/Warsaw"}`
+
+	testCases := []struct {
+		name            string
+		extension       string
+		content         []byte
+		options         []parser.Option
+		expected        bool
+		expectedSummary models.ImportSummary
+		rejectedLines   []int
+	}{
+		{
+			name:            "NDJSON",
+			extension:       ".ndjson",
+			content:         []byte(bank + "\n\n" + `{"swiftCode": 1}` + "\n" + branch + "\n"),
+			expected:        true,
+			expectedSummary: models.ImportSummary{Inserted: 2, Failed: 1},
+			rejectedLines:   []int{3},
+		},
+		{
+			name:            "JSON array",
+			extension:       ".json",
+			content:         []byte("[" + bank + ",\n" + `{"countryISO2": ["PL"]}` + ",\n" + branch + "]"),
+			expected:        true,
+			expectedSummary: models.ImportSummary{Inserted: 2, Failed: 1},
+			rejectedLines:   []int{2},
+		},
+		{
+			name:            "NDJSON file read with format option",
+			extension:       ".txt",
+			content:         []byte(bank + "\n"),
+			options:         []parser.Option{parser.WithFormat(parser.FormatNDJSON)},
+			expected:        true,
+			expectedSummary: models.ImportSummary{Inserted: 1},
+		},
+		{
+			name:      "Malformed JSON array",
+			extension: ".json",
+			content:   []byte("[" + bank + ", {"),
+			expected:  false,
+		},
+		{
+			name:      "JSON object instead of array",
+			extension: ".json",
+			content:   []byte(bank),
+			expected:  false,
+		},
+		{
+			// short rows are padded to the width of the header row
+			name:      "XLSX",
+			extension: ".xlsx",
+			content: xlsxContent(t, [][]string{
+				correctHeaders,
+				{"PL", "BREXPLPWXXX", "BIC11", "MBANK S.A.", "UL. PROSTA 18", "WARSZAWA", "POLAND", "Europe/Warsaw"},
+				{"PL", "BREXPLPWWAW", "BIC11", "MBANK S.A."},
+			}),
+			expected:        true,
+			expectedSummary: models.ImportSummary{Inserted: 2},
+		},
+		{
+			name:      "XLSX sheet selected by name",
+			extension: ".xlsx",
+			content: xlsxContent(t, [][]string{
+				correctHeaders,
+				{"PL", "BREXPLPWXXX", "BIC11", "MBANK S.A.", "UL. PROSTA 18", "WARSZAWA", "POLAND", "Europe/Warsaw"},
+			}),
+			options:         []parser.Option{parser.WithSheet("banks")},
+			expected:        true,
+			expectedSummary: models.ImportSummary{Inserted: 1},
+		},
+		{
+			name:      "XLSX missing sheet",
+			extension: ".xlsx",
+			content:   xlsxContent(t, [][]string{correctHeaders}),
+			options:   []parser.Option{parser.WithSheet("Branches")},
+			expected:  false,
+		},
+		{
+			name:      "XLSX with cell beyond the last column",
+			extension: ".xlsx",
+			content:   xlsxPackage(t, "", `<row r="1"><c r="ZZZZZZZZZZZZZZ1" t="inlineStr"><is><t>COUNTRY ISO2 CODE</t></is></c></row>`),
+			expected:  false,
+		},
+		{
+			name:      "XLSX with unknown shared string",
+			extension: ".xlsx",
+			content:   xlsxPackage(t, "", xlsxInlineRow(1, correctHeaders)+`<row r="2"><c r="A2" t="s"><v>5</v></c></row>`),
+			expected:  false,
+		},
+		{
+			name:      "XLSX with incorrect headers",
+			extension: ".xlsx",
+			content:   xlsxContent(t, [][]string{incorrectHeaders}),
+			expected:  false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := createTempFile(t, tc.extension, tc.content)
+			defer func() {
+				if err := os.Remove(path); err != nil {
+					log.Printf("Failed to remove temp file: %v", err)
+				}
+			}()
+
+			report, err := parser.ParseCSV(&MockService{}, path, tc.options...)
+			if !tc.expected {
+				if err == nil {
+					t.Fatalf("Name: %v, expected error, got nil", tc.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Name: %v, expected nil, got %v", tc.name, err)
+			}
+			if report.ImportSummary != tc.expectedSummary {
+				t.Fatalf("Name: %v, expected summary %+v, got %+v", tc.name, tc.expectedSummary, report.ImportSummary)
+			}
+			if len(report.Rejected) != len(tc.rejectedLines) {
+				t.Fatalf("Name: %v, expected %d rejected rows, got %+v", tc.name, len(tc.rejectedLines), report.Rejected)
+			}
+			for i, line := range tc.rejectedLines {
+				if report.Rejected[i].Line != line || len(report.Rejected[i].Record) == 0 {
+					t.Fatalf("Name: %v, expected rejected line %d with record, got %+v", tc.name, line, report.Rejected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestImportJobsNDJSON(t *testing.T) {
+	content := bytes.NewBufferString(`{"countryISO2": "PL", "swiftCode": "BREXPLPWXXX", "codeType": "BIC11", "bankName": "MBANK S.A.", "address": "UL. PROSTA 18", "townName": "WARSZAWA", "countryName": "POLAND", "timeZone": "Europe/Warsaw"}` + "\n")
+
+	jobs := parser.NewImportJobs(&MockService{})
+	job, err := jobs.Start("banks.ndjson", content)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if job.TotalRows != 1 {
+		t.Fatalf("expected started job with 1 row, got %+v", job)
+	}
+	job = waitForJob(t, jobs, job.ID)
+	if job.Status != models.ImportCompleted || job.Progress.Inserted != 1 {
+		t.Fatalf("expected completed job with 1 inserted row, got %+v", job)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for name, expected := range map[string]bool{"": true, "csv": true, "NDJSON": true, "json": true, "xlsx": true, "xml": false} {
+		if _, err := parser.ParseFormat(name); (err == nil) != expected {
+			t.Fatalf("Format: %q, expected valid %v, got %v", name, expected, err)
+		}
+	}
+	for path, expected := range map[string]parser.Format{
+		"banks.csv":    parser.FormatCSV,
+		"banks.txt":    parser.FormatCSV,
+		"banks.jsonl":  parser.FormatNDJSON,
+		"banks.NDJSON": parser.FormatNDJSON,
+		"banks.json":   parser.FormatJSON,
+		"banks.xlsx":   parser.FormatXLSX,
+	} {
+		if format := parser.DetectFormat(path); format != expected {
+			t.Fatalf("Path: %v, expected format %v, got %v", path, expected, format)
+		}
+	}
+}